@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Checkpoint описывает состояние незавершенной выгрузки anime365
+type Checkpoint struct {
+	// Offset - offset API, с которого начнется следующая страница
+	Offset int `json:"offset"`
+	// Bytes - сколько байт временного файла записано до контрольной точки
+	Bytes int64 `json:"bytes"`
+}
+
+func checkpointPath(outputPath string) string {
+	return outputPath + ".checkpoint.json"
+}
+
+func loadCheckpoint(outputPath string) (Checkpoint, error) {
+	var cp Checkpoint
+
+	data, err := os.ReadFile(checkpointPath(outputPath))
+	if err != nil {
+		return cp, fmt.Errorf("failed to read checkpoint: %v", err)
+	}
+
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return cp, fmt.Errorf("failed to parse checkpoint: %v", err)
+	}
+
+	return cp, nil
+}
+
+// saveCheckpoint записывает контрольную точку атомарно: сначала во временный файл, затем rename
+func saveCheckpoint(outputPath string, cp Checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %v", err)
+	}
+
+	path := checkpointPath(outputPath)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %v", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to rename checkpoint: %v", err)
+	}
+
+	return nil
+}
+
+func removeCheckpoint(outputPath string) error {
+	if err := os.Remove(checkpointPath(outputPath)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove checkpoint: %v", err)
+	}
+	return nil
+}
+
+// openPartial открывает недописанную выгрузку и отрезает всё, что было записано после контрольной точки.
+// Файл короче контрольной точки не от этой выгрузки: дополнять его нулями нельзя.
+func openPartial(path string, size int64) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open partial output: %v", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to stat partial output: %v", err)
+	}
+	if info.Size() < size {
+		file.Close()
+		return nil, fmt.Errorf("partial output %s has %d bytes, checkpoint expects %d: start a new run without -resume", path, info.Size(), size)
+	}
+
+	if err := file.Truncate(size); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to truncate partial output: %v", err)
+	}
+
+	if _, err := file.Seek(size, 0); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to seek partial output: %v", err)
+	}
+
+	return file, nil
+}
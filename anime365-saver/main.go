@@ -1,58 +1,99 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+
+	"dimensi/db-aggregator/pkg/fetcher"
+	"dimensi/db-aggregator/pkg/ratelimiter"
 )
 
 type SeriesResponse struct {
-	Data []map[string]interface{} `json:"data"`
+	// Записи сохраняем как есть, без перекодирования через map
+	Data  []json.RawMessage `json:"data"`
+	Error *APIError         `json:"error"`
+}
+
+// APIError - ошибка, которую anime365 возвращает вместо data
+type APIError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
 }
 
 func main() {
 	// Определяем флаги командной строки
 	initialOffset := flag.Int("offset", 0, "Starting offset for fetching data")
 	batchSize := flag.Int("limit", 500, "Number of items to fetch per request")
+	outputPath := flag.String("output", "anime365-db.jsonl", "Выходной файл")
+	resume := flag.Bool("resume", false, "Продолжить прерванную выгрузку с последней контрольной точки")
+	rateLimitDir := flag.String("rate-limit-dir", "", "Директория общего лимита запросов для одновременно запущенных программ (пусто - лимит только у этого процесса)")
 	flag.Parse()
 
+	// По Ctrl-C останавливаемся, сохранив контрольную точку
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Base URL to fetch the data from
 	baseURL := "https://smotret-anime.online/api/series/?limit=%d&offset=%d"
 
-	// Open the output file
-	file, err := os.Create("anime365-db.jsonl")
+	limiter, err := ratelimiter.NewForHost(*rateLimitDir, "smotret-anime.online", 1, 30)
 	if err != nil {
-		log.Fatalf("Failed to create output file: %v", err)
+		log.Fatalf("Failed to create rate limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	client := &http.Client{Timeout: fetcher.DefaultTimeout}
+	config := fetcher.DefaultConfig()
+
+	// Пишем во временный файл и переименовываем только после последней страницы,
+	// так что прерванный запуск не портит предыдущую выгрузку
+	tmpPath := *outputPath + ".tmp"
+
+	var checkpoint Checkpoint
+	var file *os.File
+	if *resume {
+		checkpoint, err = loadCheckpoint(*outputPath)
+		if err != nil {
+			log.Fatalf("Failed to resume: %v", err)
+		}
+		file, err = openPartial(tmpPath, checkpoint.Bytes)
+		if err != nil {
+			log.Fatalf("Failed to resume: %v", err)
+		}
+		fmt.Printf("Продолжаем выгрузку с offset %d\n", checkpoint.Offset)
+	} else {
+		// Контрольная точка прошлой выгрузки относится к старому временному файлу, который сейчас будет обнулен
+		if err := removeCheckpoint(*outputPath); err != nil {
+			log.Fatalf("Failed to remove stale checkpoint: %v", err)
+		}
+		checkpoint.Offset = *initialOffset
+		file, err = os.Create(tmpPath)
+		if err != nil {
+			log.Fatalf("Failed to create output file: %v", err)
+		}
 	}
 	defer file.Close()
 
-	offset := *initialOffset
 	for {
 		// Construct the URL with the current offset and batch size
-		url := fmt.Sprintf(baseURL, *batchSize, offset)
+		url := fmt.Sprintf(baseURL, *batchSize, checkpoint.Offset)
 		fmt.Printf("Fetching data from: %s\n", url)
 
-		// Perform the HTTP GET request
-		resp, err := http.Get(url)
-		if err != nil {
-			log.Fatalf("Failed to fetch data: %v", err)
+		series, err := fetchSeries(ctx, client, url, limiter, config)
+		if ctx.Err() != nil {
+			fmt.Printf("Остановлено на offset %d, для продолжения запустите с -resume\n", checkpoint.Offset)
+			return
 		}
-		defer resp.Body.Close()
-
-		// Read the response body
-		body, err := io.ReadAll(resp.Body)
 		if err != nil {
-			log.Fatalf("Failed to read response body: %v", err)
-		}
-
-		// Parse the JSON response
-		var series SeriesResponse
-		if err := json.Unmarshal(body, &series); err != nil {
-			log.Fatalf("Failed to parse JSON: %v", err)
+			log.Fatalf("Failed to fetch data: %v. Загруженное сохранено, для продолжения запустите с -resume", err)
 		}
 
 		// Break the loop if no data is returned
@@ -61,22 +102,61 @@ func main() {
 			break
 		}
 
-		// Write each item as a JSON line
+		// Страница пишется целиком, чтобы контрольная точка всегда приходилась на ее границу
+		var batch bytes.Buffer
 		for _, item := range series.Data {
 			jsonLine, err := json.Marshal(item)
 			if err != nil {
 				log.Printf("Failed to marshal item: %v", err)
 				continue
 			}
-			_, err = file.WriteString(string(jsonLine) + "\n")
-			if err != nil {
-				log.Printf("Failed to write to file: %v", err)
-			}
+			batch.Write(jsonLine)
+			batch.WriteByte('\n')
+		}
+
+		n, err := file.Write(batch.Bytes())
+		if err != nil {
+			log.Fatalf("Failed to write to file: %v", err)
+		}
+		if err := file.Sync(); err != nil {
+			log.Fatalf("Failed to sync output file: %v", err)
 		}
 
 		// Increment the offset for the next batch
-		offset += *batchSize
+		checkpoint.Offset += *batchSize
+		checkpoint.Bytes += int64(n)
+		if err := saveCheckpoint(*outputPath, checkpoint); err != nil {
+			log.Fatalf("Failed to save checkpoint: %v", err)
+		}
+	}
+
+	if err := file.Close(); err != nil {
+		log.Fatalf("Failed to close output file: %v", err)
+	}
+	if err := os.Rename(tmpPath, *outputPath); err != nil {
+		log.Fatalf("Failed to rename output file: %v", err)
+	}
+	if err := removeCheckpoint(*outputPath); err != nil {
+		log.Printf("Ошибка при удалении контрольной точки: %v", err)
+	}
+
+	fmt.Printf("All data successfully saved to %s\n", *outputPath)
+}
+
+// fetchSeries загружает одну страницу; ошибка API в теле ответа тоже считается ошибкой
+func fetchSeries(ctx context.Context, client *http.Client, url string, limiter *ratelimiter.RateLimiter, config fetcher.Config) (SeriesResponse, error) {
+	var series SeriesResponse
+
+	body, err := fetcher.FetchWithRetry(ctx, client, url, limiter, config)
+	if err != nil {
+		return series, err
+	}
+	if err := fetcher.DecodeJSON(url, body, &series); err != nil {
+		return series, err
+	}
+	if series.Error != nil {
+		return series, fmt.Errorf("anime365 API error %d for %s: %s", series.Error.Code, url, series.Error.Message)
 	}
 
-	fmt.Println("All data successfully saved to anime365-db.jsonl")
+	return series, nil
 }
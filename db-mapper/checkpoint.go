@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	CheckpointFileName = "db-mapper.checkpoint.json"
+	CheckpointInterval = time.Second
+)
+
+// Checkpoint описывает состояние незавершенного запуска db-mapper
+type Checkpoint struct {
+	Output    string `json:"output"`
+	Base      string `json:"base,omitempty"`
+	LastID    int64  `json:"lastId"`
+	Processed int    `json:"processed"`
+	Offset    int64  `json:"offset"`
+}
+
+func checkpointPath(outputDir string) string {
+	return filepath.Join(outputDir, CheckpointFileName)
+}
+
+func loadCheckpoint(outputDir string) (Checkpoint, error) {
+	var cp Checkpoint
+
+	data, err := os.ReadFile(checkpointPath(outputDir))
+	if err != nil {
+		return cp, fmt.Errorf("failed to read checkpoint: %v", err)
+	}
+
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return cp, fmt.Errorf("failed to parse checkpoint: %v", err)
+	}
+
+	return cp, nil
+}
+
+// saveCheckpoint записывает контрольную точку атомарно: сначала во временный файл, затем rename
+func saveCheckpoint(outputDir string, cp Checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %v", err)
+	}
+
+	path := checkpointPath(outputDir)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %v", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to rename checkpoint: %v", err)
+	}
+
+	return nil
+}
+
+func removeCheckpoint(outputDir string) error {
+	if err := os.Remove(checkpointPath(outputDir)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove checkpoint: %v", err)
+	}
+	return nil
+}
+
+// openResumedOutput открывает частично записанный файл и отрезает всё, что было записано после контрольной точки
+func openResumedOutput(outputDir string, cp Checkpoint) (*os.File, error) {
+	file, err := os.OpenFile(filepath.Join(outputDir, cp.Output), os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open output file: %v", err)
+	}
+
+	if err := file.Truncate(cp.Offset); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to truncate output file: %v", err)
+	}
+
+	if _, err := file.Seek(cp.Offset, 0); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to seek output file: %v", err)
+	}
+
+	return file, nil
+}
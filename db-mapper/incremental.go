@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+
+	"dimensi/db-aggregator/pkg/anime365"
+	"dimensi/db-aggregator/pkg/db"
+)
+
+// baseSnapshot - предыдущая сборка, из которой переносятся неизменившиеся записи
+type baseSnapshot struct {
+	records map[int]baseRecord
+}
+
+type baseRecord struct {
+	anime db.Anime
+	raw   []byte
+}
+
+// anime365Fields - часть db.Anime, которая целиком строится из anime365
+type anime365Fields struct {
+	Score            string
+	Titles           map[string]string
+	AllTitles        []string
+	Type             string
+	TypeTitle        string
+	Year             int
+	Season           string
+	NumberOfEpisodes int
+	IsAiring         int
+	Descriptions     []db.Description
+	Genres           []db.Genre
+	Poster           db.Image
+	ExternalIDs      db.ExternalIDs
+	Episodes         []anime365Episode
+}
+
+type anime365Episode struct {
+	Number                int
+	Type                  string
+	Title                 string
+	FirstUploadedDateTime string
+	ID                    int
+	IsActive              int
+	SeriesID              int
+	IsFirstUploaded       int
+}
+
+// baseSchemaVersion читает версию схемы из манифеста базовой сборки, 0 если манифеста нет
+func baseSchemaVersion(path string) int {
+	data, err := os.ReadFile(db.ManifestPath(path))
+	if err != nil {
+		return 0
+	}
+
+	var manifest db.Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return 0
+	}
+	return manifest.SchemaVersion
+}
+
+func loadBaseSnapshot(path string) (*baseSnapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open base snapshot: %v", err)
+	}
+	defer file.Close()
+
+	base := &baseSnapshot{records: make(map[int]baseRecord)}
+
+	scanner := bufio.NewScanner(file)
+	buf := make([]byte, 10*1024*1024)
+	scanner.Buffer(buf, 10*1024*1024)
+	for scanner.Scan() {
+		var anime db.Anime
+		if err := json.Unmarshal(scanner.Bytes(), &anime); err != nil {
+			continue
+		}
+		raw := make([]byte, len(scanner.Bytes()))
+		copy(raw, scanner.Bytes())
+		base.records[anime.ID] = baseRecord{anime: anime, raw: raw}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read base snapshot: %v", err)
+	}
+
+	return base, nil
+}
+
+// reusable возвращает строку из базовой сборки, если запись можно перенести без повторного сбора:
+// anime365 не изменился, запись собрана теми же источниками и ролями без ошибок источников,
+// а updated_at Shikimori совпадает. updatedAt должен быть получен в обход кэша; nil отключает проверку.
+func (b *baseSnapshot) reusable(a365 anime365.Data, build db.Build, updatedAt map[int]string) ([]byte, bool) {
+	rec, ok := b.records[int(a365.ID)]
+	if !ok || a365.IsAiring == 1 {
+		return nil, false
+	}
+
+	// Новый источник или другой фильтр ролей должны дополнить запись, а сбой источника - не переноситься дальше
+	if !sameBuild(rec.anime.Build, build) {
+		return nil, false
+	}
+
+	fresh := mapToResultAnime(a365, nil)
+	if !reflect.DeepEqual(extractAnime365Fields(rec.anime), extractAnime365Fields(fresh)) {
+		return nil, false
+	}
+
+	// Если Shikimori недоступен, старые данные лучше, чем никаких
+	if updatedAt != nil {
+		current, ok := updatedAt[int(a365.MyAnimeListID)]
+		if ok && current != rec.anime.ShikimoriUpdatedAt {
+			return nil, false
+		}
+	}
+
+	return rec.raw, true
+}
+
+// reuseCandidates - MAL ID записей, которые возможно удастся перенести; для них нужен свежий updated_at
+func (b *baseSnapshot) reuseCandidates(pending []anime365.Data, build db.Build) []int {
+	ids := make([]int, 0)
+	seen := make(map[int]bool)
+	for _, a365 := range pending {
+		rec, ok := b.records[int(a365.ID)]
+		malID := int(a365.MyAnimeListID)
+		if !ok || a365.IsAiring == 1 || malID == 0 || seen[malID] || !sameBuild(rec.anime.Build, build) {
+			continue
+		}
+		seen[malID] = true
+		ids = append(ids, malID)
+	}
+	return ids
+}
+
+func sameBuild(old *db.Build, current db.Build) bool {
+	if old == nil || len(old.FailedSources) > 0 || old.Roles != current.Roles {
+		return false
+	}
+	return slices.Equal(sortedCopy(old.Sources), sortedCopy(current.Sources))
+}
+
+func sortedCopy(values []string) []string {
+	result := slices.Clone(values)
+	slices.Sort(result)
+	return result
+}
+
+func extractAnime365Fields(a db.Anime) anime365Fields {
+	fields := anime365Fields{
+		Score:            a.Score,
+		Titles:           a.Titles,
+		AllTitles:        a.AllTitles,
+		Type:             a.Type,
+		TypeTitle:        a.TypeTitle,
+		Year:             a.Year,
+		Season:           a.Season,
+		NumberOfEpisodes: a.NumberOfEpisodes,
+		IsAiring:         a.IsAiring,
+		Descriptions:     make([]db.Description, 0, len(a.Descriptions)),
+		Genres:           a.Genres,
+		Poster:           a.Poster.Anime365,
+		ExternalIDs: db.ExternalIDs{
+			Anime365:         a.ExternalIDs.Anime365,
+			MyAnimeList:      a.ExternalIDs.MyAnimeList,
+			AniDB:            a.ExternalIDs.AniDB,
+			AnimeNewsNetwork: a.ExternalIDs.AnimeNewsNetwork,
+			Imdb:             a.ExternalIDs.Imdb,
+			WorldArt:         a.ExternalIDs.WorldArt,
+			Fansubs:          a.ExternalIDs.Fansubs,
+		},
+		Episodes: make([]anime365Episode, 0, len(a.Episodes)),
+	}
+
+	// Описание AniList дописывает источник, а не anime365
+	for _, d := range a.Descriptions {
+		if d.Source != db.SourceAniList {
+			fields.Descriptions = append(fields.Descriptions, d)
+		}
+	}
+
+	for _, ep := range a.Episodes {
+		fields.Episodes = append(fields.Episodes, anime365Episode{
+			Number:                ep.Number,
+			Type:                  ep.Type,
+			Title:                 ep.Title,
+			FirstUploadedDateTime: ep.FirstUploadedDateTime,
+			ID:                    ep.ID,
+			IsActive:              ep.IsActive,
+			SeriesID:              ep.SeriesID,
+			IsFirstUploaded:       ep.IsFirstUploaded,
+		})
+	}
+
+	return fields
+}
@@ -2,38 +2,65 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"dimensi/db-aggregator/pkg/anime365"
 	"dimensi/db-aggregator/pkg/db"
-	"dimensi/db-aggregator/pkg/jikan"
-	jikanapi "dimensi/db-aggregator/pkg/jikan/api"
-	"dimensi/db-aggregator/pkg/ratelimiter"
-	"dimensi/db-aggregator/pkg/shikimori"
-	shikiapi "dimensi/db-aggregator/pkg/shikimori/api"
-)
-
-const (
-	ScreenshotsLimit = 5
-	RolesLimit       = 5
-	SimilarLimit     = 5
+	"dimensi/db-aggregator/pkg/fetcher"
+	"dimensi/db-aggregator/pkg/source"
 )
 
 func main() {
 	// Определяем флаги командной строки
 	inputDir := flag.String("input", ".", "Директория с входными файлами")
 	outputDir := flag.String("output", ".", "Директория для выходного файла")
+	resume := flag.Bool("resume", false, "Продолжить прерванный запуск с последней контрольной точки")
+	cacheDir := flag.String("cache-dir", "", "Директория для кэша HTTP-ответов (пусто - без кэша)")
+	shikimoriTTL := flag.Duration("shikimori-cache-ttl", 7*24*time.Hour, "Время жизни кэша Shikimori")
+	jikanTTL := flag.Duration("jikan-cache-ttl", 30*24*time.Hour, "Время жизни кэша Jikan")
+	anilistTTL := flag.Duration("anilist-cache-ttl", 7*24*time.Hour, "Время жизни кэша AniList")
+	kitsuTTL := flag.Duration("kitsu-cache-ttl", 30*24*time.Hour, "Время жизни кэша Kitsu")
+	basePath := flag.String("base", "", "Предыдущий db_*.jsonl для инкрементальной сборки")
+	workers := flag.Int("workers", 4, "Количество параллельных обработчиков")
+	offline := flag.Bool("offline", false, "Сокращение для -shikimori-input и -jikan-input с shikimori-db.jsonl и jikan-db.jsonl из входной директории; явно заданные -shikimori-input/-jikan-input важнее")
+	shikimoriInput := flag.String("shikimori-input", "", "Выгрузка shikimori-saver вместо Shikimori API")
+	jikanInput := flag.String("jikan-input", "", "Выгрузка jikan-saver вместо Jikan API")
+	enabledSources := flag.String("sources", DefaultSources, "Включенные источники данных через запятую")
+	roles := flag.String("roles", source.DefaultRoles, "Роли Shikimori через запятую: Main, Supporting, должности стаффа (Director, Music...) или * для всех")
+	httpTimeout := flag.Duration("http-timeout", fetcher.DefaultTimeout, "Таймаут одного HTTP-запроса к источникам")
+	rateLimitDir := flag.String("rate-limit-dir", "", "Директория общего лимита запросов для одновременно запущенных программ (пусто - лимит только у этого процесса)")
 	flag.Parse()
 
+	// По SIGINT/SIGTERM перестаем брать новые аниме и сохраняем уже собранные
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		// Повторный Ctrl-C снова завершает процесс сразу
+		stop()
+	}()
+
+	// -offline только подставляет пути по умолчанию, явно заданные выгрузки не перезаписываются
+	if *offline {
+		if *shikimoriInput == "" {
+			*shikimoriInput = filepath.Join(*inputDir, "shikimori-db.jsonl")
+		}
+		if *jikanInput == "" {
+			*jikanInput = filepath.Join(*inputDir, "jikan-db.jsonl")
+		}
+	}
+
 	// Открываем входной файл anime365
 	anime365File, err := os.Open(filepath.Join(*inputDir, "anime365-db.jsonl"))
 	if err != nil {
@@ -41,27 +68,90 @@ func main() {
 	}
 	defer anime365File.Close()
 
-	// Создаем клиенты API
-	httpClient := &http.Client{}
-	shikimoriClient := shikiapi.NewClient(httpClient, ratelimiter.New(3, 70))
-	jikanClient := jikanapi.NewClient(httpClient, ratelimiter.New(3, 60))
+	// Собираем включенные источники данных
+	roleFilter := source.ParseRoleFilter(*roles)
+	sources, err := newSourceRegistry(sourceConfig{
+		cacheDir:       *cacheDir,
+		shikimoriTTL:   *shikimoriTTL,
+		jikanTTL:       *jikanTTL,
+		anilistTTL:     *anilistTTL,
+		kitsuTTL:       *kitsuTTL,
+		shikimoriInput: *shikimoriInput,
+		jikanInput:     *jikanInput,
+		roles:          roleFilter,
+		seiyuu:         len(roleFilter) > 0 && enabledSource(*enabledSources, "shikimori"),
+		httpTimeout:    *httpTimeout,
+		rateLimitDir:   *rateLimitDir,
+	}).build(*enabledSources)
+	if err != nil {
+		log.Fatalf("Failed to create sources: %v", err)
+	}
 
-	// Создаем выходной файл с timestamp в названии
-	timestamp := time.Now().Unix()
-	outputFileName := fmt.Sprintf("db_%d.jsonl", timestamp)
-	outputPath := filepath.Join(*outputDir, outputFileName)
+	// Для инкрементальной сборки нужен Shikimori, без него изменения видны только по anime365
+	var shiki *source.Shikimori
+	for _, src := range sources {
+		if s, ok := src.(*source.Shikimori); ok {
+			shiki = s
+		}
+	}
 
 	// Создаем директорию для выходного файла, если её нет
 	if err := os.MkdirAll(*outputDir, 0755); err != nil {
 		log.Fatalf("Failed to create output directory: %v", err)
 	}
 
-	outputFile, err := os.Create(outputPath)
-	if err != nil {
-		log.Fatalf("Failed to create output file: %v", err)
+	var checkpoint Checkpoint
+	var outputFile *os.File
+	if *resume {
+		// Продолжаем запись в файл из контрольной точки
+		checkpoint, err = loadCheckpoint(*outputDir)
+		if err != nil {
+			log.Fatalf("Failed to resume: %v", err)
+		}
+
+		outputFile, err = openResumedOutput(*outputDir, checkpoint)
+		if err != nil {
+			log.Fatalf("Failed to resume: %v", err)
+		}
+		fmt.Printf("Продолжаем запись в %s после anime365 ID %d\n", checkpoint.Output, checkpoint.LastID)
+	} else {
+		// Создаем выходной файл с timestamp в названии
+		timestamp := time.Now().Unix()
+		checkpoint.Output = fmt.Sprintf("db_%d.jsonl", timestamp)
+		checkpoint.Base = *basePath
+
+		outputFile, err = os.Create(filepath.Join(*outputDir, checkpoint.Output))
+		if err != nil {
+			log.Fatalf("Failed to create output file: %v", err)
+		}
 	}
 	defer outputFile.Close()
 
+	// Загружаем предыдущую сборку для инкрементального режима
+	var base *baseSnapshot
+	if checkpoint.Base != "" {
+		// Записи старой схемы не содержат новых полей, переносить их нельзя
+		if version := baseSchemaVersion(checkpoint.Base); version != db.SchemaVersion {
+			fmt.Printf("Базовая сборка %s собрана по схеме %d вместо %d, все записи будут собраны заново\n",
+				checkpoint.Base, version, db.SchemaVersion)
+			checkpoint.Base = ""
+		}
+	}
+	if checkpoint.Base != "" {
+		fmt.Printf("Читаем базовую сборку %s\n", checkpoint.Base)
+		base, err = loadBaseSnapshot(checkpoint.Base)
+		if err != nil {
+			log.Fatalf("Failed to load base snapshot: %v", err)
+		}
+	}
+
+	sourceNames := make([]string, 0, len(sources))
+	for _, src := range sources {
+		sourceNames = append(sourceNames, src.Name())
+	}
+	report := newReport(checkpoint.Output, sourceNames)
+	build := db.Build{Sources: sourceNames, Roles: roleFilter.String()}
+
 	// Читаем данные из anime365
 	anime365Data := make([]anime365.Data, 0)
 	fmt.Println("Читаем anime365 данные")
@@ -69,9 +159,13 @@ func main() {
 	buf := make([]byte, 10*1024*1024)
 	scanner.Buffer(buf, 10*1024*1024)
 	anime365Count := 0
+	line := 0
 	for scanner.Scan() {
+		line++
 		var data anime365.Data
 		if err := json.Unmarshal(scanner.Bytes(), &data); err != nil {
+			log.Printf("Пропущена строка %d anime365-db.jsonl: %v", line, err)
+			report.addInputError(line, err)
 			continue
 		}
 		anime365Data = append(anime365Data, data)
@@ -79,31 +173,118 @@ func main() {
 		fmt.Printf("\ranime365 Count: %d", anime365Count)
 	}
 
+	// Пропускаем аниме, которые уже были записаны до контрольной точки
+	pending := anime365Data
+	if *resume {
+		pending = skipProcessed(anime365Data, checkpoint.LastID)
+		if pending == nil {
+			log.Fatalf("Failed to resume: anime365 ID %d not found in input", checkpoint.LastID)
+		}
+	}
+
+	// Свежий updated_at Shikimori для переносимых записей получаем заранее пачками,
+	// а не отдельным запросом на каждое аниме
+	var shikimoriUpdatedAt map[int]string
+	if base != nil && shiki != nil {
+		ids := base.reuseCandidates(pending, build)
+		fmt.Printf("\nПроверяем updated_at Shikimori для %d аниме\n", len(ids))
+		shikimoriUpdatedAt, err = shiki.UpdatedAt(ctx, ids)
+		if err != nil {
+			log.Printf("Не удалось получить updated_at Shikimori для части аниме: %v", err)
+		}
+	}
+
 	// Обработка каждого аниме
 	totalAnime := anime365Count
-	fmt.Printf("\nНачинаю обработку %d аниме...\n", totalAnime)
-
-	processed := 0
-	for _, a365 := range anime365Data {
+	fmt.Printf("\nНачинаю обработку %d аниме...\n", len(pending))
+
+	processed := checkpoint.Processed
+	lastCheckpoint := time.Time{}
+
+	// Сбор данных идет параллельно, каждый источник ограничен своим лимитером
+	process := func(a365 anime365.Data) (mapped mappedAnime) {
+		// Ошибка в данных одного аниме не должна ронять всю сборку
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("Ошибка обработки аниме %d (MAL ID %d): %v", a365.ID, a365.MyAnimeListID, r)
+				mapped = mappedAnime{failed: true, failCategory: CategoryPanic, failReason: fmt.Sprint(r)}
+			}
+		}()
 
-		// Получаем данные Shikimori
-		shikiData, hasShiki := shikimoriClient.FetchAnimeData(int(a365.MyAnimeListID))
+		// Неизменившиеся записи переносим из базовой сборки как есть
+		if base != nil {
+			if raw, ok := base.reusable(a365, build, shikimoriUpdatedAt); ok {
+				return mappedAnime{line: raw, reused: true}
+			}
+		}
 
-		// Получаем данные Jikan
-		jikanData, hasJikan := jikanClient.FetchAnimeData(int(a365.MyAnimeListID))
+		req := source.Request{
+			IDs: source.IDs{
+				Anime365:    int(a365.ID),
+				MyAnimeList: int(a365.MyAnimeListID),
+				AniDB:       int(a365.AniDBID),
+			},
+			Fresh: a365.IsAiring == 1,
+		}
+		contributions, errs := fetchContributions(ctx, sources, req)
+		// Ошибки после отмены - это прерванные запросы, а не ответ источника: такую запись не пишем
+		if ctx.Err() != nil && len(errs) > 0 {
+			return mappedAnime{interrupted: true}
+		}
+		mapped = mappedAnime{errors: errs}
+		for _, c := range contributions {
+			mapped.fetched = append(mapped.fetched, c.source)
+		}
 
-		resultAnime := mapToResultAnime(a365, shikiData, hasShiki, jikanData, hasJikan)
+		resultAnime := mapToResultAnime(a365, contributions)
+		resultAnime.Build = &db.Build{Sources: build.Sources, Roles: build.Roles, FailedSources: failedSources(errs)}
 
-		// Записываем результат в файл
 		jsonData, err := json.Marshal(resultAnime)
 		if err != nil {
 			log.Printf("Ошибка маршалинга для MAL ID %d: %v", int(a365.MyAnimeListID), err)
-			continue
+			mapped.failed, mapped.failCategory, mapped.failReason = true, CategoryMarshal, err.Error()
+			return mapped
 		}
-		outputFile.WriteString(string(jsonData) + "\n")
+		mapped.line = jsonData
+		return mapped
+	}
 
-		// Обновляем прогресс
+	// Запись идет строго в порядке входного файла.
+	// На первой прерванной записи останавливаемся, чтобы контрольная точка не перескочила через нее.
+	interrupted := false
+	emit := func(a365 anime365.Data, mapped mappedAnime) bool {
+		if mapped.interrupted {
+			interrupted = true
+			return false
+		}
+		report.addRecord(int(a365.ID), int(a365.MyAnimeListID), mapped)
+		if mapped.failed {
+			return true
+		}
+
+		// Записываем результат в файл
+		n, err := outputFile.WriteString(string(mapped.line) + "\n")
+		if err != nil {
+			log.Fatalf("Failed to write output file: %v", err)
+		}
+
+		// Сохраняем контрольную точку только после того, как запись попала на диск.
+		// Не чаще раза в CheckpointInterval: fsync на каждую запись тормозит офлайн-сборку.
 		processed++
+		checkpoint.LastID = a365.ID
+		checkpoint.Processed = processed
+		checkpoint.Offset += int64(n)
+		if time.Since(lastCheckpoint) >= CheckpointInterval {
+			if err := outputFile.Sync(); err != nil {
+				log.Fatalf("Failed to sync output file: %v", err)
+			}
+			if err := saveCheckpoint(*outputDir, checkpoint); err != nil {
+				log.Fatalf("Failed to save checkpoint: %v", err)
+			}
+			lastCheckpoint = time.Now()
+		}
+
+		// Обновляем прогресс
 		if processed%10 == 0 || processed == totalAnime {
 			progress := float64(processed) / float64(totalAnime) * 100
 			fmt.Printf("\rПрогресс: [%-50s] %.1f%% (%d/%d)",
@@ -113,8 +294,54 @@ func main() {
 				totalAnime,
 			)
 		}
+		return true
+	}
+
+	runOrdered(ctx, pending, *workers, process, emit)
+	if (interrupted || ctx.Err() != nil) && processed == 0 {
+		// Не записано ни одной строки: продолжать нечего, пустой снимок и контрольная точка не нужны
+		outputFile.Close()
+		if err := os.Remove(filepath.Join(*outputDir, checkpoint.Output)); err != nil {
+			log.Printf("Ошибка при удалении пустого снимка: %v", err)
+		}
+		if err := removeCheckpoint(*outputDir); err != nil {
+			log.Printf("Ошибка при удалении контрольной точки: %v", err)
+		}
+		fmt.Println("\nОстановлено до первой записи, снимок не создан")
+		return
+	}
+	if interrupted || ctx.Err() != nil {
+		// Контрольную точку сохраняем сразу, не дожидаясь CheckpointInterval
+		if err := outputFile.Sync(); err != nil {
+			log.Fatalf("Failed to sync output file: %v", err)
+		}
+		if err := saveCheckpoint(*outputDir, checkpoint); err != nil {
+			log.Fatalf("Failed to save checkpoint: %v", err)
+		}
+		fmt.Printf("\nОстановлено после %d аниме, для продолжения запустите с -resume\n", processed)
+		fmt.Println(report.summary())
+		return
 	}
 	fmt.Println("\nОбработка завершена!")
+	fmt.Println(report.summary())
+
+	if err := removeCheckpoint(*outputDir); err != nil {
+		log.Printf("Ошибка при удалении контрольной точки: %v", err)
+	}
+
+	outputPath := filepath.Join(*outputDir, checkpoint.Output)
+	if err := writeReport(reportPath(outputPath), report); err != nil {
+		log.Printf("Ошибка при записи отчета: %v", err)
+	}
+
+	// Манифест пишем последним: его наличие означает, что снимок полностью готов
+	manifest, err := writeManifest(outputPath)
+	if err != nil {
+		log.Printf("Ошибка при записи манифеста: %v", err)
+	} else {
+		fmt.Printf("Манифест: %d записей, Shikimori: %d, Jikan: %d, sha256: %s\n",
+			manifest.Records, manifest.Sources.Shikimori, manifest.Sources.Jikan, manifest.SHA256)
+	}
 
 	// Получаем информацию о размере файла
 	fileInfo, err := outputFile.Stat()
@@ -126,12 +353,38 @@ func main() {
 	}
 }
 
-func mapToResultAnime(a365 anime365.Data, shiki shikimori.Data, hasShiki bool,
-	jikan jikan.Data, hasJikan bool) db.Anime {
+// mappedAnime - готовая строка выходного файла вместе со сведениями для отчета;
+// failed - запись пропущена из-за ошибки, interrupted - сбор прерван сигналом и запись нужно собрать заново
+type mappedAnime struct {
+	line         []byte
+	reused       bool
+	interrupted  bool
+	failed       bool
+	failCategory string
+	failReason   string
+	fetched      []string
+	errors       []sourceError
+}
+
+// skipProcessed возвращает записи, идущие после lastID, или nil, если lastID не найден.
+// lastID 0 - ни одна запись еще не записана, начинаем сначала.
+func skipProcessed(anime365Data []anime365.Data, lastID int64) []anime365.Data {
+	if lastID == 0 {
+		return anime365Data
+	}
+	for i, a365 := range anime365Data {
+		if a365.ID == lastID {
+			return anime365Data[i+1:]
+		}
+	}
+	return nil
+}
 
+func mapToResultAnime(a365 anime365.Data, contributions []fetchedContribution) db.Anime {
 	resultAnime := db.Anime{
 		ID:               int(a365.ID),
 		MyAnimeListID:    int(a365.MyAnimeListID),
+		ExternalIDs:      mapExternalIDs(a365),
 		Type:             string(a365.Type),
 		TypeTitle:        a365.TypeTitle,
 		IsAiring:         int(a365.IsAiring),
@@ -144,6 +397,7 @@ func mapToResultAnime(a365 anime365.Data, shiki shikimori.Data, hasShiki bool,
 			"romaji": strings.TrimSpace(a365.Titles.Romaji),
 			"ru":     strings.TrimSpace(a365.Titles.Ru),
 		},
+		AllTitles:    mapAllTitles(a365.AllTitles),
 		Descriptions: mapDescriptions(a365.Descriptions),
 		Score:        a365.MyAnimeListScore,
 		Trailers:     []db.Video{},
@@ -151,46 +405,55 @@ func mapToResultAnime(a365 anime365.Data, shiki shikimori.Data, hasShiki bool,
 		Genres:       mapGenres(a365.Genres),
 		Roles:        []db.Role{},
 		Screenshots:  []db.Screenshot{},
+		Episodes:     mapEpisodes(a365.Episodes),
 		Similar:      []db.Similar{},
+		Relations:    []db.Relation{},
+		Sources:      []string{db.SourceAnime365},
 	}
 
-	// Маппинг данных из Shikimori
-	if hasShiki {
-		resultAnime.AiredOn = shiki.ShikimoriData.AiredOn
-		resultAnime.ReleasedOn = shiki.ShikimoriData.ReleasedOn
-		resultAnime.Duration = int(shiki.ShikimoriData.Duration)
-		resultAnime.Roles = mapRoles(shiki.Roles)
-		resultAnime.Screenshots = mapScreenshotsBase(shiki.ShikimoriData.Screenshots)
-		// resultAnime.Screenshots = mapScreenshots(shiki.Screenshots, ScreenshotsLimit)
-		resultAnime.Similar = mapSimilar(shiki.Similar, SimilarLimit)
-		resultAnime.Studios = mapStudios(shiki.ShikimoriData.Studios)
-		resultAnime.Trailers = mapTrailers(shiki.ShikimoriData.Videos)
+	// Маппинг постера
+	resultAnime.Poster.Anime365 = db.Image{
+		Original: a365.PosterURL,
+		Preview:  a365.PosterURLSmall,
 	}
 
-	// Маппинг данных из Jikan
-	if hasJikan && len(jikan.Episodes) > 0 {
-		resultAnime.Episodes = mapEpisodesFromJikan(a365.Episodes, jikan.Episodes)
-	} else {
-		resultAnime.Episodes = mapEpisodesWithoutJikan(a365.Episodes)
+	// Вклады уже упорядочены по приоритету источников
+	for _, c := range contributions {
+		c.contribution.Apply(&resultAnime)
+		resultAnime.Sources = append(resultAnime.Sources, c.source)
 	}
 
-	// Маппинг постера
-	resultAnime.Poster = db.Poster{
-		Anime365: db.Image{
-			Original: a365.PosterURL,
-			Preview:  a365.PosterURLSmall,
-		},
-		Shikimori: db.Image{
-			Original: "https://shikimori.one" + shiki.ShikimoriData.Image.Original,
-			Preview:  "https://shikimori.one" + shiki.ShikimoriData.Image.Preview,
-			X48:      "https://shikimori.one" + shiki.ShikimoriData.Image.X48,
-			X96:      "https://shikimori.one" + shiki.ShikimoriData.Image.X96,
-		},
+	return resultAnime
+}
+
+// mapExternalIDs переносит идентификаторы, которые знает anime365; остальные дописывают источники
+func mapExternalIDs(a365 anime365.Data) db.ExternalIDs {
+	return db.ExternalIDs{
+		Anime365:         int(a365.ID),
+		MyAnimeList:      int(a365.MyAnimeListID),
+		AniDB:            int(a365.AniDBID),
+		AnimeNewsNetwork: int(a365.AnimeNewsNetworkID),
+		Imdb:             int(a365.ImdbID),
+		WorldArt:         int(a365.WorldArtID),
+		Fansubs:          int(a365.FansubsID),
 	}
+}
 
-	// Маппинг жанров и студий
+// mapAllTitles сохраняет все альтернативные названия без пустых и повторяющихся
+func mapAllTitles(titles []string) []string {
+	result := make([]string, 0, len(titles))
+	seen := make(map[string]bool, len(titles))
 
-	return resultAnime
+	for _, t := range titles {
+		t = strings.TrimSpace(t)
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		result = append(result, t)
+	}
+
+	return result
 }
 
 func mapGenres(genres []anime365.Genre) []db.Genre {
@@ -207,89 +470,6 @@ func mapGenres(genres []anime365.Genre) []db.Genre {
 	return result
 }
 
-func mapStudios(studios []shikimori.Studio) []db.Studio {
-	result := make([]db.Studio, 0, len(studios))
-
-	for _, s := range studios {
-		result = append(result, db.Studio{
-			ID:           s.ID,
-			FilteredName: s.FilteredName,
-			Image:        s.Image,
-			Name:         s.Name,
-			Real:         s.Real,
-		})
-	}
-
-	return result
-}
-
-func mapRoles(roles []map[string]interface{}) []db.Role {
-	result := make([]db.Role, 0, len(roles))
-
-	for _, r := range roles {
-		// Проверяем, есть ли роль "Main" среди ролей персонажа
-		isMain := false
-		if roles, ok := r["roles"].([]interface{}); ok {
-			for _, r := range roles {
-				if strings.ToLower(r.(string)) == "main" {
-					isMain = true
-					break
-				}
-			}
-		}
-
-		// Пропускаем персонажа, если у него нет роли "Main"
-		if !isMain {
-			continue
-		}
-
-		role := db.Role{}
-
-		// Маппинг персонажа
-		if char, ok := r["character"].(map[string]interface{}); ok {
-			role.Character = db.Character{
-				ID:      int(char["id"].(float64)),
-				Name:    char["name"].(string),
-				Russian: char["russian"].(string),
-			}
-
-			if img, ok := char["image"].(map[string]interface{}); ok {
-				role.Character.Image = db.Image{
-					Original: "https://shikimori.one" + getString(img, "original"),
-					Preview:  "https://shikimori.one" + getString(img, "preview"),
-					X48:      "https://shikimori.one" + getString(img, "x48"),
-					X96:      "https://shikimori.one" + getString(img, "x96"),
-				}
-			}
-		}
-
-		// Маппинг ролей
-		if roles, ok := r["roles"].([]interface{}); ok {
-			role.RoleNames = make([]db.RoleName, 0, len(roles))
-			for _, r := range roles {
-				role.RoleNames = append(role.RoleNames, db.RoleName{
-					Name:    r.(string),
-					Russian: "", // Будет заполнено позже
-				})
-			}
-		}
-
-		// Маппинг русских названий ролей
-		if rolesRu, ok := r["roles_russian"].([]interface{}); ok {
-			// Добавляем русские названия к существующим ролям
-			for i, r := range rolesRu {
-				if i < len(role.RoleNames) {
-					role.RoleNames[i].Russian = r.(string)
-				}
-			}
-		}
-
-		result = append(result, role)
-	}
-
-	return result
-}
-
 func mapDescriptions(descriptions []anime365.Description) []db.Description {
 	result := make([]db.Description, 0, len(descriptions))
 
@@ -304,159 +484,7 @@ func mapDescriptions(descriptions []anime365.Description) []db.Description {
 	return result
 }
 
-func mapTrailers(trailers []shikimori.Video) []db.Video {
-	result := make([]db.Video, 0, len(trailers))
-
-	for _, t := range trailers {
-		result = append(result, db.Video{
-			Hosting:   t.Hosting,
-			ID:        t.ID,
-			ImageURL:  t.ImageURL,
-			Kind:      t.Kind,
-			Name:      t.Name,
-			PlayerURL: t.PlayerURL,
-			URL:       t.URL,
-		})
-	}
-
-	return result
-}
-
-func mapScreenshotsBase(screenshots []shikimori.Screenshot) []db.Screenshot {
-	result := make([]db.Screenshot, 0, len(screenshots))
-
-	for _, s := range screenshots {
-		result = append(result, db.Screenshot{
-			Original: s.Original,
-			Preview:  s.Preview,
-		})
-	}
-
-	return result
-}
-
-// func mapScreenshots(screenshots []map[string]interface{}, limit int) []db.Screenshot {
-// 	result := make([]db.Screenshot, 0, len(screenshots))
-
-// 	for _, s := range screenshots {
-// 		screenshot := db.Screenshot{}
-
-// 		if original, ok := s["original"].(string); ok {
-// 			screenshot.Original = original
-// 		}
-// 		if preview, ok := s["preview"].(string); ok {
-// 			screenshot.Preview = preview
-// 		}
-
-// 		result = append(result, screenshot)
-
-// 		if len(result) >= limit {
-// 			break
-// 		}
-// 	}
-
-// 	return result
-// }
-
-func mapSimilar(similar []map[string]interface{}, limit int) []db.Similar {
-	result := make([]db.Similar, 0, limit)
-
-	for _, s := range similar {
-		sim := db.Similar{
-			MyAnimeListID: getInt(s, "id"),
-			Titles: map[string]string{
-				"en": getString(s, "name"),
-				"ru": getString(s, "russian"),
-			},
-			Score: getString(s, "score"),
-		}
-
-		// Маппинг изображения
-		if img, ok := s["image"].(map[string]interface{}); ok {
-			sim.Image = db.Image{
-				Original: "https://shikimori.one" + getString(img, "original"),
-				Preview:  "https://shikimori.one" + getString(img, "preview"),
-				X48:      "https://shikimori.one" + getString(img, "x48"),
-				X96:      "https://shikimori.one" + getString(img, "x96"),
-			}
-		}
-
-		result = append(result, sim)
-
-		if len(result) >= limit {
-			break
-		}
-	}
-
-	return result
-}
-
-// Вспомогательные функции для безопасного получения значений
-func getString(m map[string]interface{}, key string) string {
-	if val, ok := m[key].(string); ok {
-		return strings.TrimSpace(val)
-	}
-	return ""
-}
-
-func getInt(m map[string]interface{}, key string) int {
-	switch v := m[key].(type) {
-	case float64:
-		return int(v)
-	case int:
-		return v
-	case int64:
-		return int(v)
-	default:
-		return 0
-	}
-}
-
-func mapEpisodesFromJikan(a365Episodes []anime365.Episode, jikanEpisodes []jikan.Episode) []db.Episode {
-	result := make([]db.Episode, 0, len(a365Episodes))
-
-	for _, ep := range a365Episodes {
-		// Конвертируем строку в число
-		episodeNum, _ := strconv.Atoi(ep.EpisodeInt)
-
-		// Ищем соответствующий эпизод в Jikan данных
-		var jikanEp *jikan.Episode
-		for _, jEp := range jikanEpisodes {
-			if fmt.Sprint(jEp.MalID) == ep.EpisodeInt {
-				jikanEp = &jEp
-				break
-			}
-		}
-
-		resultEp := db.Episode{
-			Number:                episodeNum,
-			Type:                  string(ep.EpisodeType),
-			Title:                 ep.EpisodeTitle,
-			FirstUploadedDateTime: ep.FirstUploadedDateTime,
-			ID:                    int(ep.ID),
-			IsActive:              int(ep.IsActive),
-			SeriesID:              int(ep.SeriesID),
-			IsFirstUploaded:       int(ep.IsFirstUploaded),
-		}
-
-		// Добавляем данные из Jikan если они есть
-		if jikanEp != nil {
-			resultEp.AirDate = jikanEp.Aired
-			resultEp.Titles = map[string]string{
-				"en":     strings.TrimSpace(jikanEp.Title),
-				"ja":     strings.TrimSpace(jikanEp.TitleJapanese),
-				"romaji": strings.TrimSpace(jikanEp.TitleRomanji),
-			}
-			resultEp.Rating = fmt.Sprintf("%.2f", jikanEp.Score)
-		}
-
-		result = append(result, resultEp)
-	}
-
-	return result
-}
-
-func mapEpisodesWithoutJikan(a365Episodes []anime365.Episode) []db.Episode {
+func mapEpisodes(a365Episodes []anime365.Episode) []db.Episode {
 	result := make([]db.Episode, 0, len(a365Episodes))
 
 	for _, ep := range a365Episodes {
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"dimensi/db-aggregator/pkg/db"
+)
+
+// writeManifest пересчитывает манифест по готовому файлу, поэтому он верен и после -resume и -base
+func writeManifest(outputPath string) (db.Manifest, error) {
+	manifest := db.Manifest{
+		File:          filepath.Base(outputPath),
+		SchemaVersion: db.SchemaVersion,
+	}
+
+	name := strings.TrimSuffix(strings.TrimPrefix(manifest.File, "db_"), ".jsonl")
+	manifest.CreatedAt, _ = strconv.ParseInt(name, 10, 64)
+	if manifest.CreatedAt == 0 {
+		manifest.CreatedAt = time.Now().Unix()
+	}
+
+	file, err := os.Open(outputPath)
+	if err != nil {
+		return manifest, fmt.Errorf("failed to open output file: %v", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	counter := &countingWriter{}
+	scanner := bufio.NewScanner(io.TeeReader(file, io.MultiWriter(hash, counter)))
+	buf := make([]byte, 10*1024*1024)
+	scanner.Buffer(buf, 10*1024*1024)
+	for scanner.Scan() {
+		var anime struct {
+			Sources []string `json:"sources"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &anime); err != nil {
+			continue
+		}
+
+		manifest.Records++
+		if slices.Contains(anime.Sources, db.SourceAnime365) {
+			manifest.Sources.Anime365++
+		}
+		if slices.Contains(anime.Sources, db.SourceShikimori) {
+			manifest.Sources.Shikimori++
+		}
+		if slices.Contains(anime.Sources, db.SourceJikan) {
+			manifest.Sources.Jikan++
+		}
+		if slices.Contains(anime.Sources, db.SourceAniList) {
+			manifest.Sources.AniList++
+		}
+		if slices.Contains(anime.Sources, db.SourceKitsu) {
+			manifest.Sources.Kitsu++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return manifest, fmt.Errorf("failed to read output file: %v", err)
+	}
+
+	manifest.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	manifest.Size = counter.n
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, fmt.Errorf("failed to marshal manifest: %v", err)
+	}
+
+	manifestPath := db.ManifestPath(outputPath)
+	tmpPath := manifestPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return manifest, fmt.Errorf("failed to write manifest: %v", err)
+	}
+	if err := os.Rename(tmpPath, manifestPath); err != nil {
+		return manifest, fmt.Errorf("failed to rename manifest: %v", err)
+	}
+
+	return manifest, nil
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
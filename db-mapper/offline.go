@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"dimensi/db-aggregator/pkg/jikan"
+	"dimensi/db-aggregator/pkg/shikimori"
+	"dimensi/db-aggregator/pkg/source"
+)
+
+type shikimoriDump map[int]shikimori.Data
+
+func (d shikimoriDump) FetchAnimeData(ctx context.Context, malID int) (shikimori.Data, error) {
+	data, ok := d[malID]
+	if !ok {
+		return data, source.ErrNoData
+	}
+	return data, nil
+}
+
+func (d shikimoriDump) FetchUpdatedAt(ctx context.Context, malIDs []int) (map[int]time.Time, error) {
+	result := make(map[int]time.Time, len(malIDs))
+	for _, malID := range malIDs {
+		if data, ok := d[malID]; ok {
+			result[malID] = data.ShikimoriData.UpdatedAt
+		}
+	}
+	return result, nil
+}
+
+type jikanDump map[int]jikan.Data
+
+func (d jikanDump) FetchAnimeData(ctx context.Context, malID int) (jikan.Data, error) {
+	data, ok := d[malID]
+	if !ok {
+		return data, source.ErrNoData
+	}
+	return data, nil
+}
+
+func loadShikimoriDump(path string) (shikimoriDump, error) {
+	return loadDump(path, func(d shikimori.Data) int { return d.MyAnimeListID })
+}
+
+func loadJikanDump(path string) (jikanDump, error) {
+	return loadDump(path, func(d jikan.Data) int { return d.MyAnimeListID })
+}
+
+// loadDump читает JSONL-выгрузку сейвера в словарь по MAL ID
+func loadDump[T any](path string, malID func(T) int) (map[int]T, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dump: %v", err)
+	}
+	defer file.Close()
+
+	result := make(map[int]T)
+	skipped := 0
+
+	scanner := bufio.NewScanner(file)
+	buf := make([]byte, 10*1024*1024)
+	scanner.Buffer(buf, 10*1024*1024)
+	for scanner.Scan() {
+		var data T
+		if err := json.Unmarshal(scanner.Bytes(), &data); err != nil {
+			skipped++
+			continue
+		}
+		if id := malID(data); id != 0 {
+			result[id] = data
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dump: %v", err)
+	}
+	if skipped > 0 {
+		log.Printf("В выгрузке %s пропущено нечитаемых строк: %d", path, skipped)
+	}
+
+	return result, nil
+}
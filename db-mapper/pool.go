@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// aheadPerWorker - на сколько элементов на обработчика раздача может обогнать запись.
+// Без ограничения одно застрявшее в повторах аниме копило бы в буфере все результаты за ним.
+const aheadPerWorker = 4
+
+type poolResult[R any] struct {
+	index  int
+	result R
+}
+
+// runOrdered обрабатывает items в workers горутинах и вызывает emit в исходном порядке.
+// emit выполняется в вызывающей горутине, поэтому запись в файл не требует синхронизации.
+// После отмены ctx новые элементы не раздаются, уже начатые дорабатывают; если emit вернул false,
+// остальные результаты отбрасываются.
+func runOrdered[T, R any](ctx context.Context, items []T, workers int, process func(T) R, emit func(T, R) bool) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan int)
+	results := make(chan poolResult[R], workers)
+	// Место освобождается, когда элемент записан или отброшен после остановки
+	slots := make(chan struct{}, workers*aheadPerWorker)
+	// halt закрывается, когда emit попросил остановиться
+	halt := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				results <- poolResult[R]{index: index, result: process(items[index])}
+			}
+		}()
+	}
+
+	go func() {
+	dispatch:
+		for index := range items {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				break dispatch
+			case <-halt:
+				break dispatch
+			}
+			select {
+			case jobs <- index:
+			case <-ctx.Done():
+				break dispatch
+			case <-halt:
+				break dispatch
+			}
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	// Результаты, пришедшие раньше своей очереди, ждут в буфере
+	buffered := make(map[int]R)
+	next := 0
+	stopped := false
+	for res := range results {
+		if stopped {
+			continue
+		}
+		buffered[res.index] = res.result
+		for !stopped {
+			result, ok := buffered[next]
+			if !ok {
+				break
+			}
+			delete(buffered, next)
+			stopped = !emit(items[next], result)
+			next++
+			<-slots
+		}
+		if stopped {
+			close(halt)
+		}
+	}
+}
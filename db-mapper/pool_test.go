@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func sequence(n int) []int {
+	items := make([]int, n)
+	for i := range items {
+		items[i] = i
+	}
+	return items
+}
+
+// runWithTimeout падает, если runOrdered не вернулся: значит, пул завис
+func runWithTimeout(t *testing.T, run func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		run()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("runOrdered did not return")
+	}
+}
+
+func checkPrefix(t *testing.T, emitted []int) {
+	t.Helper()
+	for i, item := range emitted {
+		if item != i {
+			t.Fatalf("emitted %v, want a contiguous prefix of items", emitted)
+		}
+	}
+}
+
+func TestRunOrderedKeepsOrder(t *testing.T) {
+	items := sequence(200)
+	var emitted []int
+
+	runWithTimeout(t, func() {
+		runOrdered(context.Background(), items, 8, func(item int) int {
+			// Поздние элементы быстрее ранних, чтобы результаты приходили не по порядку
+			time.Sleep(time.Duration((len(items)-item)%7) * time.Millisecond)
+			return item * 10
+		}, func(item, result int) bool {
+			if result != item*10 {
+				t.Errorf("item %d got result %d", item, result)
+			}
+			emitted = append(emitted, item)
+			return true
+		})
+	})
+
+	if len(emitted) != len(items) {
+		t.Fatalf("emitted %d items, want %d", len(emitted), len(items))
+	}
+	checkPrefix(t, emitted)
+}
+
+func TestRunOrderedStopsWhenEmitReturnsFalse(t *testing.T) {
+	items := sequence(200)
+	var emitted []int
+
+	runWithTimeout(t, func() {
+		runOrdered(context.Background(), items, 4, func(item int) int {
+			return item
+		}, func(item, _ int) bool {
+			emitted = append(emitted, item)
+			return item < 10
+		})
+	})
+
+	if len(emitted) != 11 {
+		t.Fatalf("emitted %v, want items 0..10", emitted)
+	}
+	checkPrefix(t, emitted)
+}
+
+func TestRunOrderedCancelWithSlowItem(t *testing.T) {
+	const workers, slow = 2, 3
+	items := sequence(200)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var started atomic.Int32
+	var emitted []int
+
+	go func() {
+		// Даем раздаче упереться в ограничение и отменяем
+		time.Sleep(100 * time.Millisecond)
+		if n := started.Load(); n > slow+workers*aheadPerWorker {
+			t.Errorf("started %d items while item %d blocks, want at most %d", n, slow, slow+workers*aheadPerWorker)
+		}
+		cancel()
+	}()
+
+	runWithTimeout(t, func() {
+		runOrdered(ctx, items, workers, func(item int) int {
+			started.Add(1)
+			if item == slow {
+				<-ctx.Done()
+			}
+			return item
+		}, func(item, _ int) bool {
+			emitted = append(emitted, item)
+			return true
+		})
+	})
+
+	if len(emitted) <= slow || len(emitted) == len(items) {
+		t.Fatalf("emitted %d items, want more than %d and less than %d", len(emitted), slow, len(items))
+	}
+	checkPrefix(t, emitted)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"dimensi/db-aggregator/pkg/source"
+)
+
+// sourceFactory создает источник только если он включен, чтобы выключенные не грузили выгрузки и клиенты
+type sourceFactory func() (source.Source, error)
+
+type registry struct {
+	names     []string
+	factories map[string]sourceFactory
+}
+
+func newRegistry() *registry {
+	return &registry{factories: make(map[string]sourceFactory)}
+}
+
+func (r *registry) register(name string, factory sourceFactory) {
+	r.names = append(r.names, name)
+	r.factories[name] = factory
+}
+
+// enabledSource сообщает, есть ли name в списке источников через запятую
+func enabledSource(enabled, name string) bool {
+	for _, n := range strings.Split(enabled, ",") {
+		if strings.TrimSpace(n) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// build создает перечисленные через запятую источники в порядке применения вкладов
+func (r *registry) build(enabled string) ([]source.Source, error) {
+	sources := make([]source.Source, 0)
+	seen := make(map[string]bool)
+
+	for _, name := range strings.Split(enabled, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		factory, ok := r.factories[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown source %q, available: %s", name, strings.Join(r.names, ", "))
+		}
+
+		src, err := factory()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create source %s: %v", name, err)
+		}
+		sources = append(sources, src)
+	}
+
+	source.SortByPriority(sources)
+	return sources, nil
+}
+
+// fetchedContribution - вклад вместе с именем источника для db.Anime.Sources
+type fetchedContribution struct {
+	source       string
+	contribution source.Contribution
+}
+
+// fetchContributions опрашивает источники по очереди; ошибки одного источника не мешают остальным
+// и возвращаются отдельно для отчета. Неполный вклад применяется, а его ошибка все равно попадает в отчет.
+func fetchContributions(ctx context.Context, sources []source.Source, req source.Request) ([]fetchedContribution, []sourceError) {
+	result := make([]fetchedContribution, 0, len(sources))
+	var errs []sourceError
+
+	for _, src := range sources {
+		contribution, err := src.Fetch(ctx, req)
+		if err != nil {
+			errs = append(errs, sourceError{source: src.Name(), err: err})
+		}
+		if contribution != nil {
+			result = append(result, fetchedContribution{source: src.Name(), contribution: contribution})
+		}
+	}
+
+	return result, errs
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"dimensi/db-aggregator/pkg/fetcher"
+	"dimensi/db-aggregator/pkg/source"
+)
+
+// Категории ошибок в отчете
+const (
+	CategoryNoData      = "no_data"
+	CategoryNotFound    = "not_found"
+	CategoryRateLimited = "rate_limited"
+	CategoryUpstream    = "upstream"
+	CategoryHTTPStatus  = "http_status"
+	CategoryDecode      = "decode"
+	CategoryNetwork     = "network"
+	CategoryPanic       = "panic"
+	CategoryMarshal     = "marshal"
+	CategoryOther       = "other"
+)
+
+// retryableCategories - временные сбои, после которых аниме стоит собрать заново
+var retryableCategories = map[string]bool{
+	CategoryRateLimited: true,
+	CategoryUpstream:    true,
+	CategoryNetwork:     true,
+}
+
+// Report - машиночитаемый итог запуска db-mapper, пишется рядом со снимком как db_<ts>.report.json.
+// После -resume отчет описывает только записи, обработанные в последнем запуске.
+type Report struct {
+	Output      string                   `json:"output"`
+	StartedAt   int64                    `json:"startedAt"`
+	FinishedAt  int64                    `json:"finishedAt"`
+	Total       int                      `json:"total"`
+	Written     int                      `json:"written"`
+	Reused      int                      `json:"reused"`
+	Failed      int                      `json:"failed"`
+	InputErrors []InputError             `json:"inputErrors"`
+	Records     []RecordError            `json:"records"`
+	Sources     map[string]*SourceReport `json:"sources"`
+	// Retry - MAL ID пропущенных аниме и аниме, по которым источник не ответил из-за временного сбоя
+	Retry []int `json:"retry"`
+}
+
+// InputError - строка anime365-db.jsonl, которую не удалось разобрать
+type InputError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+type RecordError struct {
+	Anime365ID int    `json:"anime365Id"`
+	MalID      int    `json:"malId"`
+	Category   string `json:"category"`
+	Error      string `json:"error"`
+}
+
+type SourceReport struct {
+	Fetched  int            `json:"fetched"`
+	NoData   int            `json:"noData"`
+	Failures map[string]int `json:"failures"`
+	Errors   []RecordError  `json:"errors"`
+}
+
+// sourceError - ошибка одного источника по одному аниме
+type sourceError struct {
+	source string
+	err    error
+}
+
+func newReport(output string, sourceNames []string) *Report {
+	report := &Report{
+		Output:      output,
+		StartedAt:   time.Now().Unix(),
+		InputErrors: make([]InputError, 0),
+		Records:     make([]RecordError, 0),
+		Sources:     make(map[string]*SourceReport),
+		Retry:       make([]int, 0),
+	}
+	for _, name := range sourceNames {
+		report.Sources[name] = &SourceReport{Failures: make(map[string]int), Errors: make([]RecordError, 0)}
+	}
+	return report
+}
+
+func (r *Report) addInputError(line int, err error) {
+	r.InputErrors = append(r.InputErrors, InputError{Line: line, Error: err.Error()})
+}
+
+// addRecord учитывает результат обработки одного аниме; вызывается из emit, поэтому без блокировок
+func (r *Report) addRecord(anime365ID, malID int, mapped mappedAnime) {
+	r.Total++
+	retry := false
+
+	switch {
+	case mapped.failed:
+		r.Failed++
+		r.Records = append(r.Records, RecordError{
+			Anime365ID: anime365ID,
+			MalID:      malID,
+			Category:   mapped.failCategory,
+			Error:      mapped.failReason,
+		})
+		retry = true
+	case mapped.reused:
+		r.Written++
+		r.Reused++
+	default:
+		r.Written++
+	}
+
+	for _, name := range mapped.fetched {
+		r.Sources[name].Fetched++
+	}
+
+	for _, se := range mapped.errors {
+		sr := r.Sources[se.source]
+		// 404 значит то же, что и пустой ответ: источнику нечего добавить
+		category := errorCategory(se.err)
+		if category == CategoryNoData || category == CategoryNotFound {
+			sr.NoData++
+			continue
+		}
+
+		sr.Failures[category]++
+		sr.Errors = append(sr.Errors, RecordError{
+			Anime365ID: anime365ID,
+			MalID:      malID,
+			Category:   category,
+			Error:      se.err.Error(),
+		})
+		if retryableCategories[category] {
+			retry = true
+		}
+	}
+
+	if retry && malID != 0 {
+		r.Retry = append(r.Retry, malID)
+	}
+}
+
+// errorCategory раскладывает ошибки клиентов по категориям отчета
+func errorCategory(err error) string {
+	var statusErr *fetcher.StatusError
+	var netErr net.Error
+
+	switch {
+	case errors.Is(err, source.ErrNoData):
+		return CategoryNoData
+	case errors.Is(err, fetcher.ErrNotFound):
+		return CategoryNotFound
+	case errors.Is(err, fetcher.ErrRateLimited):
+		return CategoryRateLimited
+	case errors.Is(err, fetcher.ErrUpstream):
+		return CategoryUpstream
+	case errors.As(err, &statusErr):
+		return CategoryHTTPStatus
+	case errors.Is(err, fetcher.ErrDecode):
+		return CategoryDecode
+	case errors.As(err, &netErr):
+		return CategoryNetwork
+	default:
+		return CategoryOther
+	}
+}
+
+// failedSources - источники, не ответившие из-за ошибки; отсутствие данных и 404 ошибкой не считаются
+func failedSources(errs []sourceError) []string {
+	var failed []string
+	for _, se := range errs {
+		if category := errorCategory(se.err); category != CategoryNoData && category != CategoryNotFound {
+			failed = append(failed, se.source)
+		}
+	}
+	return failed
+}
+
+// reportPath возвращает путь отчета для db_<ts>.jsonl
+func reportPath(snapshotPath string) string {
+	return strings.TrimSuffix(snapshotPath, ".jsonl") + ".report.json"
+}
+
+func writeReport(path string, report *Report) error {
+	report.FinishedAt = time.Now().Unix()
+	sort.Ints(report.Retry)
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %v", err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write report: %v", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to rename report: %v", err)
+	}
+
+	return nil
+}
+
+// summary - короткая сводка для консоли
+func (r *Report) summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Записано: %d, перенесено: %d, пропущено из-за ошибок: %d, ошибок во входных данных: %d",
+		r.Written, r.Reused, r.Failed, len(r.InputErrors))
+
+	names := make([]string, 0, len(r.Sources))
+	for name := range r.Sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sr := r.Sources[name]
+		failed := 0
+		for _, n := range sr.Failures {
+			failed += n
+		}
+		fmt.Fprintf(&b, "\n%s: получено %d, нет данных %d, ошибок %d", name, sr.Fetched, sr.NoData, failed)
+	}
+
+	if len(r.Retry) > 0 {
+		fmt.Fprintf(&b, "\nНужно повторить: %d аниме", len(r.Retry))
+	}
+	return b.String()
+}
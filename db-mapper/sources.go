@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	anilistapi "dimensi/db-aggregator/pkg/anilist/api"
+	"dimensi/db-aggregator/pkg/fetcher"
+	jikanapi "dimensi/db-aggregator/pkg/jikan/api"
+	kitsuapi "dimensi/db-aggregator/pkg/kitsu/api"
+	"dimensi/db-aggregator/pkg/ratelimiter"
+	shikiapi "dimensi/db-aggregator/pkg/shikimori/api"
+	"dimensi/db-aggregator/pkg/source"
+)
+
+// DefaultSources не включает anilist и kitsu: они работают только через API и заметно замедляют полный прогон
+const DefaultSources = "shikimori,jikan"
+
+type sourceConfig struct {
+	cacheDir       string
+	shikimoriTTL   time.Duration
+	jikanTTL       time.Duration
+	anilistTTL     time.Duration
+	kitsuTTL       time.Duration
+	shikimoriInput string
+	jikanInput     string
+	roles          source.RoleFilter
+	// seiyuu - нужны ли Jikan персонажи: актеров озвучки он привязывает к ролям Shikimori
+	seiyuu       bool
+	httpTimeout  time.Duration
+	rateLimitDir string
+}
+
+// newSourceRegistry регистрирует все известные источники.
+// Каждый источник берется либо из выгрузки, либо из API; HTTP-клиент и кэш создаются только для API.
+func newSourceRegistry(cfg sourceConfig) *registry {
+	httpClient := &http.Client{Timeout: cfg.httpTimeout}
+
+	var cache *fetcher.Cache
+	openCache := func() (*fetcher.Cache, error) {
+		if cfg.cacheDir == "" || cache != nil {
+			return cache, nil
+		}
+		var err error
+		cache, err = fetcher.NewCache(cfg.cacheDir)
+		return cache, err
+	}
+
+	r := newRegistry()
+
+	r.register("shikimori", func() (source.Source, error) {
+		if cfg.shikimoriInput != "" {
+			dump, err := loadShikimoriDump(cfg.shikimoriInput)
+			if err != nil {
+				return nil, err
+			}
+			fmt.Printf("Выгрузка Shikimori: %d аниме\n", len(dump))
+			shiki := source.NewShikimori(dump, dump)
+			shiki.SetRoleFilter(cfg.roles)
+			return shiki, nil
+		}
+
+		limiter, err := ratelimiter.NewForHost(cfg.rateLimitDir, "shikimori.one", 3, 70)
+		if err != nil {
+			return nil, err
+		}
+		client := shikiapi.NewClient(httpClient, limiter)
+		cache, err := openCache()
+		if err != nil {
+			return nil, err
+		}
+		if cache != nil {
+			client.SetCache(cache, cfg.shikimoriTTL)
+		}
+		// Онгоинги всегда перепроверяем: условный запрос дешевый, а данные меняются каждую неделю
+		shiki := source.NewShikimori(client, client.WithCacheTTL(0))
+		shiki.SetRoleFilter(cfg.roles)
+		return shiki, nil
+	})
+
+	r.register("jikan", func() (source.Source, error) {
+		if cfg.jikanInput != "" {
+			dump, err := loadJikanDump(cfg.jikanInput)
+			if err != nil {
+				return nil, err
+			}
+			fmt.Printf("Выгрузка Jikan: %d аниме\n", len(dump))
+			return source.NewJikan(dump, dump), nil
+		}
+
+		limiter, err := ratelimiter.NewForHost(cfg.rateLimitDir, "api.jikan.moe", 3, 60)
+		if err != nil {
+			return nil, err
+		}
+		client := jikanapi.NewClient(httpClient, limiter)
+		client.SetCharacters(cfg.seiyuu)
+		cache, err := openCache()
+		if err != nil {
+			return nil, err
+		}
+		if cache != nil {
+			client.SetCache(cache, cfg.jikanTTL)
+		}
+		return source.NewJikan(client, client.WithCacheTTL(0)), nil
+	})
+
+	r.register("anilist", func() (source.Source, error) {
+		// Официальный лимит AniList - 90 запросов в минуту, но при нагрузке он снижается до 30
+		limiter, err := ratelimiter.NewForHost(cfg.rateLimitDir, "graphql.anilist.co", 1, 30)
+		if err != nil {
+			return nil, err
+		}
+		client := anilistapi.NewClient(httpClient, limiter)
+		cache, err := openCache()
+		if err != nil {
+			return nil, err
+		}
+		if cache != nil {
+			client.SetCache(cache, cfg.anilistTTL)
+		}
+		return source.NewAniList(client, client.WithCacheTTL(0)), nil
+	})
+
+	r.register("kitsu", func() (source.Source, error) {
+		limiter, err := ratelimiter.NewForHost(cfg.rateLimitDir, "kitsu.app", 3, 60)
+		if err != nil {
+			return nil, err
+		}
+		client := kitsuapi.NewClient(httpClient, limiter)
+		cache, err := openCache()
+		if err != nil {
+			return nil, err
+		}
+		if cache != nil {
+			client.SetCache(cache, cfg.kitsuTTL)
+		}
+		return source.NewKitsu(client, client.WithCacheTTL(0)), nil
+	})
+
+	return r
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"dimensi/db-aggregator/pkg/db"
+)
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 500
+)
+
+type AnimeList struct {
+	Date  int64      `json:"date"`
+	Total int        `json:"total"`
+	Page  int        `json:"page"`
+	Limit int        `json:"limit"`
+	Items []db.Anime `json:"items"`
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *Server) getAnime(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid anime ID", http.StatusBadRequest)
+		return
+	}
+
+	anime, ok := s.store.ByID(id)
+	if !ok {
+		http.Error(w, "Anime not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, anime)
+}
+
+func (s *Server) getAnimeByMAL(w http.ResponseWriter, r *http.Request) {
+	malID, err := strconv.Atoi(r.PathValue("malId"))
+	if err != nil {
+		http.Error(w, "Invalid MAL ID", http.StatusBadRequest)
+		return
+	}
+
+	anime, ok := s.store.ByMAL(malID)
+	if !ok {
+		http.Error(w, "Anime not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, anime)
+}
+
+type ExternalLookup struct {
+	Source     string `json:"source"`
+	ExternalID int    `json:"externalId"`
+	IDs        []int  `json:"ids"`
+}
+
+// lookupExternal по идентификатору в другой базе (anidb, shikimori, anilist...) находит наши ID аниме
+func (s *Server) lookupExternal(w http.ResponseWriter, r *http.Request) {
+	source := r.PathValue("source")
+	externalID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid external ID", http.StatusBadRequest)
+		return
+	}
+
+	ids, ok := s.store.Lookup(source, externalID)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unknown source %q", source), http.StatusBadRequest)
+		return
+	}
+	if len(ids) == 0 {
+		http.Error(w, "Anime not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, ExternalLookup{Source: strings.ToLower(source), ExternalID: externalID, IDs: ids})
+}
+
+func (s *Server) listAnime(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	page, limit, err := parsePagination(query.Get("page"), query.Get("limit"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	match, err := parseAnimeFilter(query.Get)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	items, total := s.store.List(match, (page-1)*limit, limit)
+	writeJSON(w, AnimeList{
+		Date:  s.store.Date(),
+		Total: total,
+		Page:  page,
+		Limit: limit,
+		Items: items,
+	})
+}
+
+type badRequest string
+
+func (e badRequest) Error() string {
+	return string(e)
+}
+
+func parsePagination(pageParam, limitParam string) (int, int, error) {
+	page, limit := 1, DefaultPageLimit
+
+	if pageParam != "" {
+		p, err := strconv.Atoi(pageParam)
+		if err != nil || p < 1 {
+			return 0, 0, badRequest("Invalid page parameter")
+		}
+		page = p
+	}
+
+	if limitParam != "" {
+		l, err := strconv.Atoi(limitParam)
+		if err != nil || l < 1 || l > MaxPageLimit {
+			return 0, 0, badRequest("Invalid limit parameter")
+		}
+		limit = l
+	}
+
+	return page, limit, nil
+}
+
+// parseAnimeFilter собирает фильтр из параметров запроса, пустые параметры не ограничивают выборку.
+// Жанр и студию можно передать как ID или как название.
+func parseAnimeFilter(get func(string) string) (func(a *db.Anime) bool, error) {
+	year, err := parseOptionalInt(get, "year")
+	if err != nil {
+		return nil, err
+	}
+	isAiring, err := parseOptionalInt(get, "isAiring")
+	if err != nil {
+		return nil, err
+	}
+
+	season := get("season")
+	animeType := get("type")
+	genre := get("genre")
+	studio := get("studio")
+
+	return func(a *db.Anime) bool {
+		if year != nil && a.Year != *year {
+			return false
+		}
+		if isAiring != nil && a.IsAiring != *isAiring {
+			return false
+		}
+		if season != "" && !strings.EqualFold(a.Season, season) {
+			return false
+		}
+		if animeType != "" && !strings.EqualFold(a.Type, animeType) {
+			return false
+		}
+		if genre != "" && !hasGenre(a.Genres, genre) {
+			return false
+		}
+		if studio != "" && !hasStudio(a.Studios, studio) {
+			return false
+		}
+		return true
+	}, nil
+}
+
+func parseOptionalInt(get func(string) string, name string) (*int, error) {
+	param := get(name)
+	if param == "" {
+		return nil, nil
+	}
+
+	value, err := strconv.Atoi(param)
+	if err != nil {
+		return nil, badRequest("Invalid " + name + " parameter")
+	}
+	return &value, nil
+}
+
+func hasGenre(genres []db.Genre, value string) bool {
+	for _, g := range genres {
+		if strconv.Itoa(g.ID) == value || strings.EqualFold(g.Title, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasStudio(studios []db.Studio, value string) bool {
+	for _, st := range studios {
+		if strconv.Itoa(st.ID) == value || strings.EqualFold(st.Name, value) || strings.EqualFold(st.FilteredName, value) {
+			return true
+		}
+	}
+	return false
+}
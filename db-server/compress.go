@@ -0,0 +1,137 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+type encoding struct {
+	name string
+	ext  string
+}
+
+// Порядок задает предпочтение, если клиент принимает несколько кодировок
+var encodings = []encoding{
+	{name: "zstd", ext: ".zst"},
+	{name: "gzip", ext: ".gz"},
+}
+
+// ensureVariants запускает фоновое сжатие снимка, если какого-то варианта ещё нет
+func (s *Server) ensureVariants(fileName string) {
+	srcPath := filepath.Join(s.dbDir, fileName)
+	srcInfo, err := os.Stat(srcPath)
+	if err != nil {
+		return
+	}
+
+	for _, enc := range encodings {
+		dstPath := srcPath + enc.ext
+		if info, err := os.Stat(dstPath); err == nil && !info.ModTime().Before(srcInfo.ModTime()) {
+			continue
+		}
+
+		s.compressMu.Lock()
+		if s.compressing[dstPath] {
+			s.compressMu.Unlock()
+			continue
+		}
+		s.compressing[dstPath] = true
+		s.compressMu.Unlock()
+
+		go func(enc encoding) {
+			defer func() {
+				s.compressMu.Lock()
+				delete(s.compressing, dstPath)
+				s.compressMu.Unlock()
+			}()
+
+			if err := compressFile(srcPath, dstPath, enc.name); err != nil {
+				log.Printf("Failed to compress %s with %s: %v", fileName, enc.name, err)
+				return
+			}
+			log.Printf("Compressed %s with %s", fileName, enc.name)
+		}(enc)
+	}
+}
+
+func compressFile(srcPath, dstPath, encodingName string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return fmt.Errorf("failed to open source: %v", err)
+	}
+	defer src.Close()
+
+	// Пишем во временный файл, чтобы никогда не отдать клиенту недожатый вариант
+	tmpPath := dstPath + ".tmp"
+	dst, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create variant: %v", err)
+	}
+	defer os.Remove(tmpPath)
+	defer dst.Close()
+
+	var w io.WriteCloser
+	switch encodingName {
+	case "gzip":
+		w, err = gzip.NewWriterLevel(dst, gzip.BestCompression)
+	case "zstd":
+		w, err = zstd.NewWriter(dst, zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+	default:
+		err = fmt.Errorf("unknown encoding %s", encodingName)
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(w, src); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to compress: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to finish compression: %v", err)
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("failed to close variant: %v", err)
+	}
+
+	return os.Rename(tmpPath, dstPath)
+}
+
+// negotiateEncoding выбирает лучшую кодировку из Accept-Encoding среди уже готовых вариантов
+func (s *Server) negotiateEncoding(acceptEncoding, filePath string) (encoding, bool) {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		params := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+		quality := 1.0
+		for _, param := range params[1:] {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				quality, _ = strconv.ParseFloat(value, 64)
+			}
+		}
+		accepted[name] = quality > 0
+	}
+
+	for _, enc := range encodings {
+		ok, listed := accepted[enc.name]
+		if !listed {
+			ok = accepted["*"]
+		}
+		if !ok {
+			continue
+		}
+		if _, err := os.Stat(filePath + enc.ext); err == nil {
+			return enc, true
+		}
+	}
+
+	return encoding{}, false
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+type Delta struct {
+	From    int64             `json:"from"`
+	To      int64             `json:"to"`
+	Added   []json.RawMessage `json:"added"`
+	Removed []int             `json:"removed"`
+	Changed []ChangedAnime    `json:"changed"`
+}
+
+// ChangedAnime содержит только изменившиеся поля верхнего уровня, удаленные поля приходят как null
+type ChangedAnime struct {
+	ID     int                        `json:"id"`
+	Fields map[string]json.RawMessage `json:"fields"`
+}
+
+type snapshotRecord struct {
+	raw    json.RawMessage
+	fields map[string]json.RawMessage
+}
+
+func (s *Server) snapshotPath(date int64) string {
+	return filepath.Join(s.dbDir, fmt.Sprintf("db_%d.jsonl", date))
+}
+
+// deltaPath включает версии обоих снимков, чтобы пересобранный или дописанный снимок не получил старую дельту
+func (s *Server) deltaPath(from, to DBFile) string {
+	return filepath.Join(s.dbDir, fmt.Sprintf("delta_%d_%d_%s.json", from.Date, to.Date, deltaVersion(from, to)))
+}
+
+func deltaVersion(from, to DBFile) string {
+	sum := sha256.Sum256([]byte(from.version + "\n" + to.version))
+	return hex.EncodeToString(sum[:6])
+}
+
+// cleanupDeltas удаляет дельты удаленных снимков и дельты устаревших версий
+func (s *Server) cleanupDeltas(files []DBFile) {
+	s.deltaMu.Lock()
+	defer s.deltaMu.Unlock()
+
+	byDate := make(map[int64]DBFile, len(files))
+	for _, f := range files {
+		byDate[f.Date] = f
+	}
+
+	entries, err := os.ReadDir(s.dbDir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		matches := s.deltaRegexp.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			continue
+		}
+		from, _ := strconv.ParseInt(matches[1], 10, 64)
+		to, _ := strconv.ParseInt(matches[2], 10, 64)
+
+		fromFile, okFrom := byDate[from]
+		toFile, okTo := byDate[to]
+		if okFrom && okTo && matches[3] == deltaVersion(fromFile, toFile) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.dbDir, entry.Name())); err != nil {
+			log.Printf("Failed to remove stale delta %s: %v", entry.Name(), err)
+		}
+	}
+}
+
+func (s *Server) getDelta(w http.ResponseWriter, r *http.Request) {
+	from, err := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid from parameter", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.updateDBList(); err != nil {
+		http.Error(w, "Failed to update DB list", http.StatusInternalServerError)
+		return
+	}
+
+	latest, ok := s.latestDB()
+	if !ok {
+		http.Error(w, "No DB files found", http.StatusNotFound)
+		return
+	}
+
+	to := latest.Date
+	if toParam := r.URL.Query().Get("to"); toParam != "" {
+		to, err = strconv.ParseInt(toParam, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid to parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	files := make([]DBFile, 0, 2)
+	for _, date := range []int64{from, to} {
+		file, ok := s.findDB(date)
+		if !ok {
+			http.Error(w, fmt.Sprintf("Snapshot %d not found", date), http.StatusNotFound)
+			return
+		}
+		// Дельта по недописанному снимку была бы неполной
+		if !file.ready {
+			http.Error(w, fmt.Sprintf("Snapshot %d is not complete yet", date), http.StatusConflict)
+			return
+		}
+		files = append(files, file)
+	}
+
+	path, err := s.ensureDelta(files[0], files[1])
+	if err != nil {
+		http.Error(w, "Failed to build delta", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	http.ServeFile(w, r, path)
+}
+
+// ensureDelta строит дельту один раз на пару версий снимков и сохраняет её рядом с ними
+func (s *Server) ensureDelta(from, to DBFile) (string, error) {
+	s.deltaMu.Lock()
+	defer s.deltaMu.Unlock()
+
+	path := s.deltaPath(from, to)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	delta, err := buildDelta(s.snapshotPath(from.Date), s.snapshotPath(to.Date))
+	if err != nil {
+		return "", err
+	}
+	delta.From = from.Date
+	delta.To = to.Date
+
+	data, err := json.Marshal(delta)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal delta: %v", err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write delta: %v", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return "", fmt.Errorf("failed to rename delta: %v", err)
+	}
+
+	return path, nil
+}
+
+func buildDelta(fromPath, toPath string) (Delta, error) {
+	delta := Delta{
+		Added:   make([]json.RawMessage, 0),
+		Removed: make([]int, 0),
+		Changed: make([]ChangedAnime, 0),
+	}
+
+	oldRecords, _, err := readSnapshot(fromPath)
+	if err != nil {
+		return delta, err
+	}
+	newRecords, order, err := readSnapshot(toPath)
+	if err != nil {
+		return delta, err
+	}
+
+	for _, id := range order {
+		newRec := newRecords[id]
+		oldRec, ok := oldRecords[id]
+		if !ok {
+			delta.Added = append(delta.Added, newRec.raw)
+			continue
+		}
+
+		changed := make(map[string]json.RawMessage)
+		for name, value := range newRec.fields {
+			if !bytes.Equal(oldRec.fields[name], value) {
+				changed[name] = value
+			}
+		}
+		for name := range oldRec.fields {
+			if _, ok := newRec.fields[name]; !ok {
+				changed[name] = json.RawMessage("null")
+			}
+		}
+
+		if len(changed) > 0 {
+			delta.Changed = append(delta.Changed, ChangedAnime{ID: id, Fields: changed})
+		}
+	}
+
+	for id := range oldRecords {
+		if _, ok := newRecords[id]; !ok {
+			delta.Removed = append(delta.Removed, id)
+		}
+	}
+	sort.Ints(delta.Removed)
+
+	return delta, nil
+}
+
+// readSnapshot читает снимок без разбора вложенных структур, порядок записей сохраняется
+func readSnapshot(path string) (map[int]snapshotRecord, []int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open snapshot: %v", err)
+	}
+	defer file.Close()
+
+	records := make(map[int]snapshotRecord)
+	order := make([]int, 0)
+
+	scanner := bufio.NewScanner(file)
+	buf := make([]byte, 10*1024*1024)
+	scanner.Buffer(buf, 10*1024*1024)
+	for scanner.Scan() {
+		raw := make([]byte, len(scanner.Bytes()))
+		copy(raw, scanner.Bytes())
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			continue
+		}
+
+		var id int
+		if err := json.Unmarshal(fields["id"], &id); err != nil {
+			continue
+		}
+
+		if _, ok := records[id]; !ok {
+			order = append(order, id)
+		}
+		records[id] = snapshotRecord{raw: raw, fields: fields}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read snapshot: %v", err)
+	}
+
+	return records, order, nil
+}
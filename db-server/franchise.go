@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+type Franchise struct {
+	Franchise string           `json:"franchise,omitempty"`
+	Items     []FranchiseEntry `json:"items"`
+}
+
+// FranchiseEntry - часть франшизы в порядке просмотра.
+// Relation - связь с запрошенным аниме, пустая для него самого и для непрямых связей.
+type FranchiseEntry struct {
+	ID            int               `json:"id"`
+	MyAnimeListID int               `json:"myAnimeListId"`
+	Titles        map[string]string `json:"titles"`
+	Type          string            `json:"type"`
+	Year          int               `json:"year"`
+	AiredOn       string            `json:"airedOn"`
+	Relation      string            `json:"relation,omitempty"`
+}
+
+// Franchise собирает все аниме франшизы Shikimori в порядке выхода.
+// Если франшиза не указана, идет по связям relations от аниме к аниме.
+func (st *Store) Franchise(id int) (Franchise, bool) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	start, ok := st.byID[id]
+	if !ok {
+		return Franchise{}, false
+	}
+	anime := &st.anime[start]
+
+	var members []int
+	if anime.Franchise != "" {
+		members = st.byFranchise[anime.Franchise]
+	} else {
+		members = st.relatedComponent(start)
+	}
+
+	relations := make(map[int]string, len(anime.Relations))
+	for _, r := range anime.Relations {
+		relations[r.MyAnimeListID] = r.Relation
+	}
+
+	result := Franchise{Franchise: anime.Franchise, Items: make([]FranchiseEntry, 0, len(members))}
+	for _, index := range members {
+		a := &st.anime[index]
+		entry := FranchiseEntry{
+			ID:            a.ID,
+			MyAnimeListID: a.MyAnimeListID,
+			Titles:        a.Titles,
+			Type:          a.Type,
+			Year:          a.Year,
+			AiredOn:       a.AiredOn,
+		}
+		if a.ID != anime.ID && a.MyAnimeListID != 0 {
+			entry.Relation = relations[a.MyAnimeListID]
+		}
+		result.Items = append(result.Items, entry)
+	}
+
+	sort.SliceStable(result.Items, func(i, j int) bool {
+		return watchOrderLess(result.Items[i], result.Items[j])
+	})
+
+	return result, true
+}
+
+// relatedComponent обходит граф связей в ширину; связи ведут по MAL ID
+func (st *Store) relatedComponent(start int) []int {
+	visited := map[int]bool{start: true}
+	queue := []int{start}
+
+	for i := 0; i < len(queue); i++ {
+		for _, r := range st.anime[queue[i]].Relations {
+			next, ok := st.byMAL[r.MyAnimeListID]
+			if !ok || visited[next] {
+				continue
+			}
+			visited[next] = true
+			queue = append(queue, next)
+		}
+	}
+
+	return queue
+}
+
+// watchOrderLess сортирует по дате выхода; без даты аниме уходит в конец своего года, без года - в конец списка
+func watchOrderLess(a, b FranchiseEntry) bool {
+	keyA, keyB := watchOrderKey(a), watchOrderKey(b)
+	if keyA != keyB {
+		return keyA < keyB
+	}
+	return a.ID < b.ID
+}
+
+func watchOrderKey(e FranchiseEntry) string {
+	if e.AiredOn != "" {
+		return e.AiredOn
+	}
+	if e.Year != 0 {
+		return fmt.Sprintf("%04d-99", e.Year)
+	}
+	return "9999"
+}
+
+func (s *Server) getFranchise(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("section") != "franchise" {
+		http.NotFound(w, r)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid anime ID", http.StatusBadRequest)
+		return
+	}
+
+	franchise, ok := s.store.Franchise(id)
+	if !ok {
+		http.Error(w, "Anime not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, franchise)
+}
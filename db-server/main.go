@@ -11,24 +11,51 @@ import (
 	"regexp"
 	"sort"
 	"strconv"
+	"sync"
+	"time"
+
+	"dimensi/db-aggregator/pkg/db"
 )
 
 type DBFile struct {
-	Date int64  `json:"date"`
-	URL  string `json:"url"`
+	Date      int64        `json:"date"`
+	URL       string       `json:"url"`
+	SQLiteURL string       `json:"sqliteUrl,omitempty"`
+	Manifest  *db.Manifest `json:"manifest,omitempty"`
+
+	// version меняется вместе с содержимым снимка: sha256 из манифеста или размер и время изменения
+	version string
+	// ready - снимок дописан: у него есть манифест, либо в директории нет манифестов вовсе (старые сборки)
+	ready bool
 }
 
 type Server struct {
-	dbDir    string
-	dbFiles  []DBFile
-	dbRegexp *regexp.Regexp
+	dbDir      string
+	dbFiles    []DBFile
+	dbFilesMu  sync.RWMutex
+	dbRegexp   *regexp.Regexp
+	fileRegexp *regexp.Regexp
+	// deltaRegexp: delta_<from>_<to>_<версия>.json
+	deltaRegexp *regexp.Regexp
+	deltaMu     sync.Mutex
+
+	compressMu  sync.Mutex
+	compressing map[string]bool
+
+	store *Store
 }
 
 func NewServer(dbDir string) *Server {
 	return &Server{
-		dbDir:    dbDir,
-		dbFiles:  make([]DBFile, 0),
-		dbRegexp: regexp.MustCompile(`^db_(\d+)\.jsonl$`),
+		dbDir:       dbDir,
+		dbFiles:     make([]DBFile, 0),
+		dbRegexp:    regexp.MustCompile(`^db_(\d+)\.jsonl$`),
+		fileRegexp:  regexp.MustCompile(`^db_(\d+)\.(jsonl|sqlite)$`),
+		deltaRegexp: regexp.MustCompile(`^delta_(\d+)_(\d+)(?:_([0-9a-f]+))?\.json$`),
+
+		compressing: make(map[string]bool),
+
+		store: NewStore(),
 	}
 }
 
@@ -48,11 +75,29 @@ func (s *Server) updateDBList() error {
 				continue
 			}
 
+			info, err := file.Info()
+			if err != nil {
+				continue
+			}
+
 			fileURL := fmt.Sprintf("/db/%s", file.Name())
-			newFiles = append(newFiles, DBFile{
-				Date: timestamp,
-				URL:  fileURL,
-			})
+			dbFile := DBFile{
+				Date:     timestamp,
+				URL:      fileURL,
+				Manifest: s.readManifest(file.Name()),
+				version:  fmt.Sprintf("%x-%x", info.ModTime().UnixNano(), info.Size()),
+			}
+			if dbFile.Manifest != nil {
+				dbFile.version = dbFile.Manifest.SHA256
+			}
+			// SQLite-экспорт появляется позже снимка, поэтому он необязателен
+			sqliteName := fmt.Sprintf("db_%d.sqlite", timestamp)
+			if _, err := os.Stat(filepath.Join(s.dbDir, sqliteName)); err == nil {
+				dbFile.SQLiteURL = fmt.Sprintf("/db/%s", sqliteName)
+				s.ensureVariants(sqliteName)
+			}
+
+			newFiles = append(newFiles, dbFile)
 		}
 	}
 
@@ -61,47 +106,144 @@ func (s *Server) updateDBList() error {
 		return newFiles[i].Date > newFiles[j].Date
 	})
 
+	// db-mapper пишет манифест последним, поэтому дописываться может только самый новый снимок без манифеста.
+	// Более старые снимки без манифеста - сборки до появления манифестов, они готовы.
+	for i := range newFiles {
+		newFiles[i].ready = newFiles[i].Manifest != nil || i > 0
+		// Недописанный снимок сжимать рано: вариант пришлось бы пережимать
+		if newFiles[i].ready {
+			s.ensureVariants(filepath.Base(newFiles[i].URL))
+		}
+	}
+
+	s.dbFilesMu.Lock()
 	s.dbFiles = newFiles
+	s.dbFilesMu.Unlock()
+
+	s.cleanupDeltas(newFiles)
 	return nil
 }
 
+// latestDB возвращает самый новый дописанный снимок из последнего прочитанного списка
+func (s *Server) latestDB() (DBFile, bool) {
+	s.dbFilesMu.RLock()
+	defer s.dbFilesMu.RUnlock()
+
+	for _, f := range s.dbFiles {
+		if f.ready {
+			return f, true
+		}
+	}
+	return DBFile{}, false
+}
+
+// findDB возвращает снимок с датой date из последнего прочитанного списка
+func (s *Server) findDB(date int64) (DBFile, bool) {
+	s.dbFilesMu.RLock()
+	defer s.dbFilesMu.RUnlock()
+
+	for _, f := range s.dbFiles {
+		if f.Date == date {
+			return f, true
+		}
+	}
+	return DBFile{}, false
+}
+
+// readManifest возвращает nil для старых снимков, собранных без манифеста
+func (s *Server) readManifest(fileName string) *db.Manifest {
+	data, err := os.ReadFile(db.ManifestPath(filepath.Join(s.dbDir, fileName)))
+	if err != nil {
+		return nil
+	}
+
+	var manifest db.Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		log.Printf("Failed to parse manifest for %s: %v", fileName, err)
+		return nil
+	}
+
+	return &manifest
+}
+
 func (s *Server) getLatestDB(w http.ResponseWriter, r *http.Request) {
 	if err := s.updateDBList(); err != nil {
 		http.Error(w, "Failed to update DB list", http.StatusInternalServerError)
 		return
 	}
 
-	if len(s.dbFiles) == 0 {
+	latest, ok := s.latestDB()
+	if !ok {
 		http.Error(w, "No DB files found", http.StatusNotFound)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(s.dbFiles[0])
+	json.NewEncoder(w).Encode(latest)
 }
 
 func (s *Server) serveDBFiles(w http.ResponseWriter, r *http.Request) {
 	filename := filepath.Base(r.URL.Path)
-	if !s.dbRegexp.MatchString(filename) {
+	matches := s.fileRegexp.FindStringSubmatch(filename)
+	if matches == nil {
 		http.Error(w, "Invalid file name", http.StatusBadRequest)
 		return
 	}
 
 	filePath := filepath.Join(s.dbDir, filename)
 
-	fileInfo, err := os.Stat(filePath)
+	// Отдаем заранее сжатый вариант, если клиент его принимает
+	w.Header().Add("Vary", "Accept-Encoding")
+	enc, compressed := s.negotiateEncoding(r.Header.Get("Accept-Encoding"), filePath)
+	servedPath := filePath
+	if compressed {
+		servedPath = filePath + enc.ext
+	}
+
+	file, err := os.Open(servedPath)
+	if err != nil {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
 	if err != nil {
 		http.Error(w, "File not found", http.StatusNotFound)
 		return
 	}
 
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", fileInfo.Size()))
-	http.ServeFile(w, r, filePath)
+	// ETag различается для каждой кодировки, иначе кэши перепутают варианты
+	tag := fmt.Sprintf("%x-%x", fileInfo.ModTime().Unix(), fileInfo.Size())
+	if matches[2] == "jsonl" {
+		if manifest := s.readManifest(filename); manifest != nil {
+			tag = manifest.SHA256
+		}
+	}
+	if compressed {
+		tag += "-" + enc.name
+		w.Header().Set("Content-Encoding", enc.name)
+
+		// При заданном Content-Encoding ServeContent не выставляет длину сам
+		if r.Header.Get("Range") == "" {
+			w.Header().Set("Content-Length", fmt.Sprintf("%d", fileInfo.Size()))
+		}
+	}
+	w.Header().Set("ETag", fmt.Sprintf("%q", tag))
+	if matches[2] == "sqlite" {
+		w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+
+	// ServeContent сам выставит Content-Length и обработает Range и If-None-Match
+	http.ServeContent(w, r, filename, fileInfo.ModTime(), file)
 }
 
 func main() {
 	dbDir := flag.String("db-dir", ".", "Directory containing DB files")
 	port := flag.Int("port", 8080, "Port to listen on")
+	reloadInterval := flag.Duration("reload-interval", time.Minute, "How often to check for a newer snapshot")
 	flag.Parse()
 
 	server := NewServer(*dbDir)
@@ -111,9 +253,25 @@ func main() {
 		log.Fatalf("Failed to initialize DB list: %v", err)
 	}
 
+	// Загружаем последний снимок в память и следим за появлением новых
+	server.reloadLatest()
+	go server.watchSnapshots(*reloadInterval)
+
 	// API endpoint для получения последнего DB файла
 	http.HandleFunc("/api/latest", server.getLatestDB)
 
+	// API endpoint для получения разницы между снимками
+	http.HandleFunc("/api/delta", server.getDelta)
+
+	// API для запросов по последнему снимку
+	http.HandleFunc("GET /api/anime", server.listAnime)
+	http.HandleFunc("GET /api/anime/{id}", server.getAnime)
+	http.HandleFunc("GET /api/anime/by-mal/{malId}", server.getAnimeByMAL)
+	// Шаблон /api/anime/{id}/franchise конфликтует с by-mal/{malId}, поэтому последний сегмент проверяет обработчик
+	http.HandleFunc("GET /api/anime/{id}/{section}", server.getFranchise)
+	http.HandleFunc("GET /api/lookup/{source}/{id}", server.lookupExternal)
+	http.HandleFunc("GET /api/search", server.searchAnime)
+
 	// Endpoint для отдачи файлов
 	http.HandleFunc("/db/", server.serveDBFiles)
 
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"dimensi/db-aggregator/pkg/db"
+)
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+
+	// Ниже этой похожести совпадение считается шумом
+	minRelevance = 0.3
+	// Доля популярности в итоговом ранге, остальное - похожесть названия
+	popularityWeight = 0.15
+)
+
+// SearchIndex - триграммный индекс по всем названиям всех языков
+type SearchIndex struct {
+	entries  []searchEntry
+	trigrams map[string][]int32
+}
+
+type searchEntry struct {
+	anime      int
+	title      string
+	normalized string
+	grams      int
+}
+
+type SearchResult struct {
+	Relevance    float64  `json:"relevance"`
+	MatchedTitle string   `json:"matchedTitle"`
+	Anime        db.Anime `json:"anime"`
+}
+
+type searchCandidate struct {
+	anime     int
+	title     string
+	relevance float64
+	rank      float64
+}
+
+// Диакритика ромадзи и европейских названий, которую пользователи не набирают
+var foldReplacer = strings.NewReplacer(
+	"ё", "е",
+	"ā", "a", "á", "a", "à", "a", "â", "a", "ä", "a", "ã", "a", "å", "a",
+	"ē", "e", "é", "e", "è", "e", "ê", "e", "ë", "e",
+	"ī", "i", "í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ō", "o", "ó", "o", "ò", "o", "ô", "o", "ö", "o", "õ", "o", "ø", "o",
+	"ū", "u", "ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ç", "c", "ñ", "n", "ß", "ss",
+)
+
+// normalizeTitle приводит название к нижнему регистру, убирает диакритику и пунктуацию
+func normalizeTitle(title string) string {
+	title = foldReplacer.Replace(strings.ToLower(title))
+
+	var b strings.Builder
+	space := true
+	for _, r := range title {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			space = false
+			continue
+		}
+		if !space {
+			b.WriteRune(' ')
+			space = true
+		}
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+func trigrams(normalized string) []string {
+	runes := []rune(" " + normalized + " ")
+	seen := make(map[string]bool, len(runes))
+	grams := make([]string, 0, len(runes))
+
+	for i := 0; i+3 <= len(runes); i++ {
+		gram := string(runes[i : i+3])
+		if !seen[gram] {
+			seen[gram] = true
+			grams = append(grams, gram)
+		}
+	}
+
+	return grams
+}
+
+func NewSearchIndex(anime []db.Anime) *SearchIndex {
+	index := &SearchIndex{
+		entries:  make([]searchEntry, 0, len(anime)*4),
+		trigrams: make(map[string][]int32),
+	}
+
+	for i, a := range anime {
+		seen := make(map[string]bool)
+		titles := make([]string, 0, len(a.Titles)+len(a.AllTitles))
+		for _, t := range a.Titles {
+			titles = append(titles, t)
+		}
+		titles = append(titles, a.AllTitles...)
+
+		for _, title := range titles {
+			normalized := normalizeTitle(title)
+			if normalized == "" || seen[normalized] {
+				continue
+			}
+			seen[normalized] = true
+
+			grams := trigrams(normalized)
+			entryID := int32(len(index.entries))
+			index.entries = append(index.entries, searchEntry{
+				anime:      i,
+				title:      strings.TrimSpace(title),
+				normalized: normalized,
+				grams:      len(grams),
+			})
+			for _, gram := range grams {
+				index.trigrams[gram] = append(index.trigrams[gram], entryID)
+			}
+		}
+	}
+
+	return index
+}
+
+// Search возвращает индексы аниме в порядке убывания ранга
+func (idx *SearchIndex) Search(query string, anime []db.Anime, limit int) []searchCandidate {
+	normalized := normalizeTitle(query)
+	if normalized == "" {
+		return nil
+	}
+
+	queryGrams := trigrams(normalized)
+	common := make(map[int32]int)
+	for _, gram := range queryGrams {
+		for _, entryID := range idx.trigrams[gram] {
+			common[entryID]++
+		}
+	}
+
+	best := make(map[int]searchCandidate)
+	for entryID, count := range common {
+		entry := idx.entries[entryID]
+		relevance := titleRelevance(normalized, entry, count, len(queryGrams))
+		if relevance < minRelevance {
+			continue
+		}
+
+		if current, ok := best[entry.anime]; !ok || relevance > current.relevance {
+			best[entry.anime] = searchCandidate{anime: entry.anime, title: entry.title, relevance: relevance}
+		}
+	}
+
+	results := make([]searchCandidate, 0, len(best))
+	for _, c := range best {
+		c.rank = c.relevance*(1-popularityWeight) + popularity(anime[c.anime])*popularityWeight
+		results = append(results, c)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].rank != results[j].rank {
+			return results[i].rank > results[j].rank
+		}
+		return anime[results[i].anime].ID < anime[results[j].anime].ID
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// titleRelevance сочетает коэффициент Дайса по триграммам с бонусами за точное и префиксное совпадение.
+// Дайс прощает опечатки, а доля покрытых триграмм запроса не штрафует длинные названия.
+func titleRelevance(query string, entry searchEntry, common, queryGrams int) float64 {
+	if entry.normalized == query {
+		return 1
+	}
+
+	dice := 2 * float64(common) / float64(queryGrams+entry.grams)
+	coverage := 0.8 * float64(common) / float64(queryGrams)
+	relevance := max(dice, coverage)
+
+	if strings.HasPrefix(entry.normalized, query) {
+		relevance = max(relevance, 0.9)
+	} else if strings.Contains(entry.normalized, query) {
+		relevance = max(relevance, 0.85)
+	}
+
+	return relevance
+}
+
+// popularity переводит оценку MAL в диапазон 0..1
+func popularity(a db.Anime) float64 {
+	score, err := strconv.ParseFloat(a.Score, 64)
+	if err != nil || score <= 0 {
+		return 0
+	}
+	return min(score/10, 1)
+}
+
+func (s *Server) searchAnime(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	if strings.TrimSpace(query) == "" {
+		http.Error(w, "Missing q parameter", http.StatusBadRequest)
+		return
+	}
+
+	limit := DefaultSearchLimit
+	if param := r.URL.Query().Get("limit"); param != "" {
+		l, err := strconv.Atoi(param)
+		if err != nil || l < 1 || l > MaxSearchLimit {
+			http.Error(w, "Invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+		limit = l
+	}
+
+	writeJSON(w, s.store.Search(query, limit))
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"dimensi/db-aggregator/pkg/db"
+)
+
+// Store держит в памяти самый новый снимок и индексы по нему
+type Store struct {
+	mu   sync.RWMutex
+	date int64
+	// version - версия загруженного снимка (см. DBFile), чтобы заметить его перезапись
+	version string
+	anime   []db.Anime
+	byID    map[int]int
+	byMAL   map[int]int
+	// byExternal: база -> внешний ID -> индексы аниме; один внешний ID бывает у нескольких записей anime365
+	byExternal  map[string]map[int][]int
+	byFranchise map[string][]int
+	index       *SearchIndex
+}
+
+func NewStore() *Store {
+	return &Store{
+		byID:        make(map[int]int),
+		byMAL:       make(map[int]int),
+		byExternal:  newExternalIndex(),
+		byFranchise: make(map[string][]int),
+		index:       NewSearchIndex(nil),
+	}
+}
+
+func (st *Store) Date() int64 {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.date
+}
+
+// Loaded сообщает, загружена ли уже эта версия снимка
+func (st *Store) Loaded(date int64, version string) bool {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.date == date && st.version == version
+}
+
+// Load читает снимок целиком и подменяет текущий только после успешного разбора
+func (st *Store) Load(path string, date int64, version string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %v", err)
+	}
+	defer file.Close()
+
+	anime := make([]db.Anime, 0)
+	byID := make(map[int]int)
+	byMAL := make(map[int]int)
+	byExternal := newExternalIndex()
+	byFranchise := make(map[string][]int)
+
+	scanner := bufio.NewScanner(file)
+	buf := make([]byte, 10*1024*1024)
+	scanner.Buffer(buf, 10*1024*1024)
+	for scanner.Scan() {
+		var a db.Anime
+		if err := json.Unmarshal(scanner.Bytes(), &a); err != nil {
+			continue
+		}
+
+		byID[a.ID] = len(anime)
+		if a.MyAnimeListID != 0 {
+			byMAL[a.MyAnimeListID] = len(anime)
+		}
+		if a.Franchise != "" {
+			byFranchise[a.Franchise] = append(byFranchise[a.Franchise], len(anime))
+		}
+		for source, id := range a.ExternalIDs.BySource() {
+			if id != 0 {
+				byExternal[source][id] = append(byExternal[source][id], len(anime))
+			}
+		}
+		anime = append(anime, a)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read snapshot: %v", err)
+	}
+
+	index := NewSearchIndex(anime)
+
+	st.mu.Lock()
+	st.date = date
+	st.version = version
+	st.anime = anime
+	st.byID = byID
+	st.byMAL = byMAL
+	st.byExternal = byExternal
+	st.byFranchise = byFranchise
+	st.index = index
+	st.mu.Unlock()
+
+	return nil
+}
+
+func (st *Store) ByID(id int) (db.Anime, bool) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	index, ok := st.byID[id]
+	if !ok {
+		return db.Anime{}, false
+	}
+	return st.anime[index], true
+}
+
+func (st *Store) ByMAL(malID int) (db.Anime, bool) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	index, ok := st.byMAL[malID]
+	if !ok {
+		return db.Anime{}, false
+	}
+	return st.anime[index], true
+}
+
+// newExternalIndex заводит пустой индекс для каждой известной внешней базы
+func newExternalIndex() map[string]map[int][]int {
+	index := make(map[string]map[int][]int)
+	for source := range (db.ExternalIDs{}).BySource() {
+		index[source] = make(map[int][]int)
+	}
+	return index
+}
+
+// Lookup возвращает наши ID аниме по идентификатору во внешней базе; ok=false для неизвестной базы
+func (st *Store) Lookup(source string, externalID int) ([]int, bool) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	ids, ok := st.byExternal[strings.ToLower(source)]
+	if !ok {
+		return nil, false
+	}
+
+	result := make([]int, 0, len(ids[externalID]))
+	for _, index := range ids[externalID] {
+		result = append(result, st.anime[index].ID)
+	}
+	return result, true
+}
+
+// List возвращает страницу аниме, прошедших фильтр, и общее количество совпадений
+func (st *Store) List(match func(a *db.Anime) bool, offset, limit int) ([]db.Anime, int) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	items := make([]db.Anime, 0, limit)
+	total := 0
+	for i := range st.anime {
+		if !match(&st.anime[i]) {
+			continue
+		}
+		if total >= offset && len(items) < limit {
+			items = append(items, st.anime[i])
+		}
+		total++
+	}
+
+	return items, total
+}
+
+func (st *Store) Search(query string, limit int) []SearchResult {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	candidates := st.index.Search(query, st.anime, limit)
+	results := make([]SearchResult, 0, len(candidates))
+	for _, c := range candidates {
+		results = append(results, SearchResult{
+			Relevance:    c.relevance,
+			MatchedTitle: c.title,
+			Anime:        st.anime[c.anime],
+		})
+	}
+
+	return results
+}
+
+// reloadLatest подгружает новый снимок, когда он дописан, и перезагружает текущий, если его перезаписали.
+// Снимок без манифеста еще пишется, latestDB его не вернет.
+func (s *Server) reloadLatest() {
+	if err := s.updateDBList(); err != nil {
+		log.Printf("Failed to update DB list: %v", err)
+		return
+	}
+
+	latest, ok := s.latestDB()
+	if !ok || s.store.Loaded(latest.Date, latest.version) {
+		return
+	}
+
+	start := time.Now()
+	if err := s.store.Load(s.snapshotPath(latest.Date), latest.Date, latest.version); err != nil {
+		log.Printf("Failed to load snapshot %d: %v", latest.Date, err)
+		return
+	}
+	log.Printf("Loaded snapshot %d in %v", latest.Date, time.Since(start))
+}
+
+func (s *Server) watchSnapshots(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.reloadLatest()
+	}
+}
@@ -16,16 +16,18 @@ echo -e "${GREEN}Начинаем деплой db-server...${NC}"
 echo "Создаем архив..."
 cp go.mod go.sum db-server/
 mkdir -p db-server/db-server
-cp db-server/main.go db-server/db-server/
+cp db-server/*.go db-server/db-server/
+cp -r pkg db-server/pkg
 tar --no-xattrs -czf db-server.tar.gz \
     db-server/Dockerfile \
     db-server/docker-compose.yml \
     db-server/db-server/ \
     db-server/nginx.conf \
+    db-server/pkg/ \
     db-server/go.mod \
     db-server/go.sum
 
-rm -rf db-server/go.mod db-server/go.sum db-server/db-server/
+rm -rf db-server/go.mod db-server/go.sum db-server/db-server/ db-server/pkg/
 
 # Копируем файлы на сервер
 echo "Копируем файлы на сервер..."
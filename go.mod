@@ -1,3 +1,8 @@
 module dimensi/db-aggregator
 
 go 1.23.4
+
+require (
+	github.com/klauspost/compress v1.18.0
+	github.com/mattn/go-sqlite3 v1.14.24
+)
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"time"
+
+	"dimensi/db-aggregator/pkg/fetcher"
+	jikanapi "dimensi/db-aggregator/pkg/jikan/api"
+	"dimensi/db-aggregator/pkg/ratelimiter"
+	"dimensi/db-aggregator/pkg/saver"
+)
+
+func main() {
+	// Флаг разбирается в saver.Main вместе с общими
+	characters := flag.Bool("characters", true, "Загружать персонажей с актерами озвучки (/characters); без них на каждое аниме на запрос меньше")
+
+	saver.Main(saver.Saver{
+		Name:      "Jikan",
+		Output:    "jikan-db.jsonl",
+		Host:      "api.jikan.moe",
+		PerSecond: 3,
+		PerMinute: 60,
+		NewFetch: func(httpClient *http.Client, limiter *ratelimiter.RateLimiter, cache *fetcher.Cache) saver.FetchFunc {
+			client := jikanapi.NewClient(httpClient, limiter)
+			client.SetCharacters(*characters)
+			if cache != nil {
+				client.SetCache(cache, 30*24*time.Hour)
+			}
+			return func(ctx context.Context, malID int) (interface{}, error) {
+				return client.FetchAnimeData(ctx, malID)
+			}
+		},
+	})
+}
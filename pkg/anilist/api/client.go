@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"dimensi/db-aggregator/pkg/anilist"
+	"dimensi/db-aggregator/pkg/fetcher"
+	"dimensi/db-aggregator/pkg/ratelimiter"
+)
+
+const mediaQuery = `query ($idMal: Int) {
+  Media(idMal: $idMal, type: ANIME) {
+    id
+    idMal
+    title { romaji english native }
+    description(asHtml: false)
+    bannerImage
+    tags { id name category rank isMediaSpoiler }
+    nextAiringEpisode { airingAt timeUntilAiring episode }
+    updatedAt
+  }
+}`
+
+type Client struct {
+	fetcher.Client
+	baseURL string
+}
+
+func NewClient(httpClient *http.Client, rateLimiter *ratelimiter.RateLimiter) *Client {
+	// Остаток лимита из заголовков X-RateLimit-* AniList учитывает сам лимитер
+	return &Client{
+		Client:  fetcher.NewClient(httpClient, rateLimiter),
+		baseURL: "https://graphql.anilist.co",
+	}
+}
+
+func (c *Client) WithCacheTTL(ttl time.Duration) *Client {
+	clone := *c
+	clone.Client = c.Client.WithCacheTTL(ttl)
+	return &clone
+}
+
+// FetchAnimeData ищет аниме по MAL ID; AniList отвечает 404, если такого нет
+func (c *Client) FetchAnimeData(ctx context.Context, malID int) (anilist.Data, error) {
+	var data anilist.Data
+	data.MyAnimeListID = malID
+
+	request, err := json.Marshal(map[string]interface{}{
+		"query":     mediaQuery,
+		"variables": map[string]int{"idMal": malID},
+	})
+	if err != nil {
+		return data, fmt.Errorf("failed to marshal query: %v", err)
+	}
+
+	var response struct {
+		Data struct {
+			Media *anilist.Media `json:"Media"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+			Status  int    `json:"status"`
+		} `json:"errors"`
+	}
+
+	if err := c.PostJSON(ctx, c.baseURL, request, &response); err != nil {
+		return data, err
+	}
+	if len(response.Errors) > 0 {
+		return data, fmt.Errorf("AniList error for MAL ID %d: %s", malID, response.Errors[0].Message)
+	}
+	if response.Data.Media == nil {
+		return data, fmt.Errorf("AniList media for MAL ID %d: %w", malID, fetcher.ErrNotFound)
+	}
+
+	data.Media = *response.Data.Media
+	return data, nil
+}
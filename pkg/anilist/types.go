@@ -0,0 +1,37 @@
+package anilist
+
+type Data struct {
+	MyAnimeListID int   `json:"myAnimeListId"`
+	Media         Media `json:"media"`
+}
+
+type Media struct {
+	ID                int                `json:"id"`
+	IDMal             int                `json:"idMal"`
+	Title             Title              `json:"title"`
+	Description       string             `json:"description"`
+	BannerImage       string             `json:"bannerImage"`
+	Tags              []Tag              `json:"tags"`
+	NextAiringEpisode *NextAiringEpisode `json:"nextAiringEpisode"`
+	UpdatedAt         int64              `json:"updatedAt"`
+}
+
+type Title struct {
+	Romaji  string `json:"romaji"`
+	English string `json:"english"`
+	Native  string `json:"native"`
+}
+
+type Tag struct {
+	ID             int    `json:"id"`
+	Name           string `json:"name"`
+	Category       string `json:"category"`
+	Rank           int    `json:"rank"`
+	IsMediaSpoiler bool   `json:"isMediaSpoiler"`
+}
+
+type NextAiringEpisode struct {
+	AiringAt        int64 `json:"airingAt"`
+	TimeUntilAiring int64 `json:"timeUntilAiring"`
+	Episode         int   `json:"episode"`
+}
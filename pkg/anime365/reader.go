@@ -0,0 +1,44 @@
+package anime365
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// ReadMALIDs возвращает уникальные MAL ID из anime365-db.jsonl в порядке файла
+func ReadMALIDs(path string) ([]int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open anime365 file: %v", err)
+	}
+	defer file.Close()
+
+	ids := make([]int, 0)
+	seen := make(map[int]bool)
+
+	scanner := bufio.NewScanner(file)
+	buf := make([]byte, 10*1024*1024)
+	scanner.Buffer(buf, 10*1024*1024)
+	for scanner.Scan() {
+		var data struct {
+			MyAnimeListID int64 `json:"myAnimeListId"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &data); err != nil {
+			continue
+		}
+
+		id := int(data.MyAnimeListID)
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read anime365 file: %v", err)
+	}
+
+	return ids, nil
+}
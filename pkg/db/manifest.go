@@ -0,0 +1,37 @@
+package db
+
+import "strings"
+
+// SchemaVersion увеличивается при каждом несовместимом изменении Anime
+const SchemaVersion = 7
+
+type Manifest struct {
+	File          string       `json:"file"`
+	CreatedAt     int64        `json:"createdAt"`
+	SHA256        string       `json:"sha256"`
+	Size          int64        `json:"size"`
+	Records       int          `json:"records"`
+	SchemaVersion int          `json:"schemaVersion"`
+	Sources       SourceCounts `json:"sources"`
+}
+
+type SourceCounts struct {
+	Anime365  int `json:"anime365"`
+	Shikimori int `json:"shikimori"`
+	Jikan     int `json:"jikan"`
+	AniList   int `json:"anilist"`
+	Kitsu     int `json:"kitsu"`
+}
+
+const (
+	SourceAnime365  = "anime365"
+	SourceShikimori = "shikimori"
+	SourceJikan     = "jikan"
+	SourceAniList   = "anilist"
+	SourceKitsu     = "kitsu"
+)
+
+// ManifestPath возвращает путь манифеста для db_<ts>.jsonl
+func ManifestPath(snapshotPath string) string {
+	return strings.TrimSuffix(snapshotPath, ".jsonl") + ".manifest.json"
+}
@@ -1,28 +1,77 @@
 package db
 
 type Anime struct {
-	ID               int               `json:"id"`
-	MyAnimeListID    int               `json:"myAnimeListId"`
-	Score            string            `json:"score"`
-	Titles           map[string]string `json:"titles"`
-	Type             string            `json:"type"`
-	TypeTitle        string            `json:"typeTitle"`
-	Year             int               `json:"year"`
-	Season           string            `json:"season"`
-	NumberOfEpisodes int               `json:"numberOfEpisodes"`
-	Duration         int               `json:"duration"`
-	IsAiring         int               `json:"isAiring"`
-	AiredOn          string            `json:"airedOn"`
-	ReleasedOn       string            `json:"releasedOn"`
-	Descriptions     []Description     `json:"descriptions"`
-	Studios          []Studio          `json:"studios"`
-	Poster           Poster            `json:"poster"`
-	Trailers         []Video           `json:"trailers"`
-	Genres           []Genre           `json:"genres"`
-	Roles            []Role            `json:"roles"`
-	Screenshots      []Screenshot      `json:"screenshots"`
-	Episodes         []Episode         `json:"episodes"`
-	Similar          []Similar         `json:"similar"`
+	ID                 int               `json:"id"`
+	MyAnimeListID      int               `json:"myAnimeListId"`
+	ExternalIDs        ExternalIDs       `json:"externalIds"`
+	Score              string            `json:"score"`
+	Titles             map[string]string `json:"titles"`
+	AllTitles          []string          `json:"allTitles"`
+	Type               string            `json:"type"`
+	TypeTitle          string            `json:"typeTitle"`
+	Year               int               `json:"year"`
+	Season             string            `json:"season"`
+	NumberOfEpisodes   int               `json:"numberOfEpisodes"`
+	Duration           int               `json:"duration"`
+	IsAiring           int               `json:"isAiring"`
+	AiredOn            string            `json:"airedOn"`
+	ReleasedOn         string            `json:"releasedOn"`
+	ShikimoriUpdatedAt string            `json:"shikimoriUpdatedAt,omitempty"`
+	NextEpisode        *NextEpisode      `json:"nextEpisode,omitempty"`
+	Descriptions       []Description     `json:"descriptions"`
+	Studios            []Studio          `json:"studios"`
+	Poster             Poster            `json:"poster"`
+	BannerImage        string            `json:"bannerImage,omitempty"`
+	Trailers           []Video           `json:"trailers"`
+	Genres             []Genre           `json:"genres"`
+	Tags               []Tag             `json:"tags,omitempty"`
+	Roles              []Role            `json:"roles"`
+	Staff              []Staff           `json:"staff,omitempty"`
+	Screenshots        []Screenshot      `json:"screenshots"`
+	Episodes           []Episode         `json:"episodes"`
+	Similar            []Similar         `json:"similar"`
+	Relations          []Relation        `json:"relations"`
+	Franchise          string            `json:"franchise,omitempty"`
+	Sources            []string          `json:"sources,omitempty"`
+	Build              *Build            `json:"build,omitempty"`
+}
+
+// Build - с какими настройками собрана запись; по нему инкрементальная сборка решает, можно ли ее перенести
+type Build struct {
+	Sources []string `json:"sources"`
+	Roles   string   `json:"roles"`
+	// FailedSources - источники, не ответившие из-за ошибки: такую запись нужно собрать заново
+	FailedSources []string `json:"failedSources,omitempty"`
+}
+
+// ExternalIDs - идентификаторы аниме в других базах, 0 если неизвестен
+type ExternalIDs struct {
+	Anime365         int `json:"anime365,omitempty"`
+	MyAnimeList      int `json:"myAnimeList,omitempty"`
+	AniDB            int `json:"aniDb,omitempty"`
+	AnimeNewsNetwork int `json:"animeNewsNetwork,omitempty"`
+	Imdb             int `json:"imdb,omitempty"`
+	WorldArt         int `json:"worldArt,omitempty"`
+	Fansubs          int `json:"fansubs,omitempty"`
+	Shikimori        int `json:"shikimori,omitempty"`
+	AniList          int `json:"aniList,omitempty"`
+	Kitsu            int `json:"kitsu,omitempty"`
+}
+
+// BySource возвращает все идентификаторы с ключами-названиями баз в нижнем регистре
+func (ids ExternalIDs) BySource() map[string]int {
+	return map[string]int{
+		"anime365":         ids.Anime365,
+		"myanimelist":      ids.MyAnimeList,
+		"anidb":            ids.AniDB,
+		"animenewsnetwork": ids.AnimeNewsNetwork,
+		"imdb":             ids.Imdb,
+		"worldart":         ids.WorldArt,
+		"fansubs":          ids.Fansubs,
+		"shikimori":        ids.Shikimori,
+		"anilist":          ids.AniList,
+		"kitsu":            ids.Kitsu,
+	}
 }
 
 type Image struct {
@@ -48,6 +97,20 @@ type Genre struct {
 	URL   string `json:"url"`
 }
 
+// Tag - тег AniList, Rank показывает насколько тег подходит аниме (0-100)
+type Tag struct {
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	Rank     int    `json:"rank"`
+	Spoiler  bool   `json:"spoiler"`
+}
+
+// NextEpisode - ближайшая серия онгоинга, AiringAt в unix-секундах
+type NextEpisode struct {
+	Episode  int   `json:"episode"`
+	AiringAt int64 `json:"airingAt"`
+}
+
 type Studio struct {
 	FilteredName string `json:"filteredName"`
 	ID           int    `json:"id"`
@@ -75,6 +138,27 @@ type RoleName struct {
 type Role struct {
 	Character Character  `json:"character"`
 	RoleNames []RoleName `json:"roleNames"`
+	Seiyuu    []Seiyuu   `json:"seiyuu,omitempty"`
+}
+
+// Person - человек из Shikimori/MAL, ID у обоих совпадают
+type Person struct {
+	ID      int    `json:"id"`
+	Image   Image  `json:"image"`
+	Name    string `json:"name"`
+	Russian string `json:"russian,omitempty"`
+}
+
+// Seiyuu - актер озвучки персонажа на одном из языков (Japanese, English...)
+type Seiyuu struct {
+	Person   Person `json:"person"`
+	Language string `json:"language"`
+}
+
+// Staff - участник производства: режиссер, композитор, автор оригинала...
+type Staff struct {
+	Person    Person     `json:"person"`
+	RoleNames []RoleName `json:"roleNames"`
 }
 
 type Character struct {
@@ -101,6 +185,9 @@ type Episode struct {
 	Titles                map[string]string `json:"titles"`
 	Rating                string            `json:"rating"`
 	IsFirstUploaded       int               `json:"isFirstUploaded"`
+	Thumbnail             string            `json:"thumbnail,omitempty"`
+	Synopsis              string            `json:"synopsis,omitempty"`
+	Length                int               `json:"length,omitempty"` // в минутах
 }
 
 type Similar struct {
@@ -109,3 +196,14 @@ type Similar struct {
 	Titles        map[string]string `json:"titles"`
 	Score         string            `json:"score"`
 }
+
+// Relation - связь с другим аниме; Relation в snake_case: sequel, prequel, side_story, spin_off...
+type Relation struct {
+	Relation        string            `json:"relation"`
+	RelationRussian string            `json:"relationRussian"`
+	MyAnimeListID   int               `json:"myAnimeListId"`
+	Titles          map[string]string `json:"titles"`
+	Kind            string            `json:"kind"`
+	AiredOn         string            `json:"airedOn"`
+	Image           Image             `json:"image"`
+}
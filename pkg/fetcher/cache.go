@@ -0,0 +1,102 @@
+package fetcher
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Cache хранит ответы на диске, ключом служит sha256 от URL
+type Cache struct {
+	dir string
+}
+
+type cacheEntry struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	FetchedAt    time.Time `json:"fetchedAt"`
+	Body         []byte    `json:"-"`
+}
+
+func NewCache(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %v", err)
+	}
+	return &Cache{dir: dir}, nil
+}
+
+// path - один файл на запись: строка метаданных JSON, за ней тело ответа как есть.
+// Так ETag всегда описывает именно то тело, что лежит рядом с ним.
+func (c *Cache) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	key := hex.EncodeToString(sum[:])
+	return filepath.Join(c.dir, key[:2], key+".entry")
+}
+
+func (c *Cache) get(url string) (cacheEntry, bool) {
+	var entry cacheEntry
+
+	data, err := os.ReadFile(c.path(url))
+	if err != nil {
+		return entry, false
+	}
+	meta, body, ok := bytes.Cut(data, []byte("\n"))
+	if !ok {
+		return entry, false
+	}
+	if err := json.Unmarshal(meta, &entry); err != nil || entry.URL != url {
+		return entry, false
+	}
+	entry.Body = body
+
+	return entry, true
+}
+
+func (c *Cache) put(entry cacheEntry) error {
+	path := c.path(entry.URL)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %v", err)
+	}
+
+	meta, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %v", err)
+	}
+
+	data := make([]byte, 0, len(meta)+1+len(entry.Body))
+	data = append(data, meta...)
+	data = append(data, '\n')
+	data = append(data, entry.Body...)
+	return writeFileAtomic(path, data)
+}
+
+// writeFileAtomic пишет через уникальный временный файл: один и тот же URL могут одновременно
+// сохранять несколько обработчиков и несколько процессов с общим кэшем
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create cache file: %v", err)
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write cache file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write cache file: %v", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to rename cache file: %v", err)
+	}
+	return nil
+}
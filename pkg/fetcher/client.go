@@ -0,0 +1,56 @@
+package fetcher
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"dimensi/db-aggregator/pkg/ratelimiter"
+)
+
+// Client - общая часть клиентов API: HTTP-клиент, лимитер и настройки повторов и кэша.
+// Клиенты источников встраивают его и делают запросы через GetJSON/PostJSON.
+type Client struct {
+	httpClient  *http.Client
+	rateLimiter *ratelimiter.RateLimiter
+	config      Config
+}
+
+func NewClient(httpClient *http.Client, rateLimiter *ratelimiter.RateLimiter) Client {
+	return Client{
+		httpClient:  httpClient,
+		rateLimiter: rateLimiter,
+		config:      DefaultConfig(),
+	}
+}
+
+// SetCache включает дисковый кэш ответов с заданным временем жизни
+func (c *Client) SetCache(cache *Cache, ttl time.Duration) {
+	c.config.Cache = cache
+	c.config.CacheTTL = ttl
+}
+
+// WithCacheTTL возвращает копию с другим временем жизни кэша; лимитер и кэш остаются общими.
+// С ttl 0 копия перепроверяет каждый ответ условным запросом, так делаются запросы для онгоингов.
+func (c Client) WithCacheTTL(ttl time.Duration) Client {
+	c.config.CacheTTL = ttl
+	return c
+}
+
+// GetJSON загружает url с повторами и разбирает ответ в v
+func (c *Client) GetJSON(ctx context.Context, url string, v interface{}) error {
+	body, err := FetchWithRetry(ctx, c.httpClient, url, c.rateLimiter, c.config)
+	if err != nil {
+		return err
+	}
+	return DecodeJSON(url, body, v)
+}
+
+// PostJSON отправляет body на url с повторами и разбирает ответ в v
+func (c *Client) PostJSON(ctx context.Context, url string, body []byte, v interface{}) error {
+	respBody, err := PostWithRetry(ctx, c.httpClient, url, body, c.rateLimiter, c.config)
+	if err != nil {
+		return err
+	}
+	return DecodeJSON(url, respBody, v)
+}
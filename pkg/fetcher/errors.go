@@ -0,0 +1,85 @@
+package fetcher
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Виды ошибок для проверки через errors.Is
+var (
+	// ErrNotFound - у источника нет такого ресурса: ответ 404 или пустой результат поиска
+	ErrNotFound = errors.New("not found")
+	// ErrRateLimited - 429, который не прошел после всех попыток
+	ErrRateLimited = errors.New("rate limited")
+	// ErrUpstream - источник отвечал 5xx до исчерпания попыток
+	ErrUpstream = errors.New("upstream error")
+	// ErrDecode - ответ не разобрался как JSON ожидаемой формы
+	ErrDecode = errors.New("decode error")
+)
+
+// StatusError - сервер ответил неожиданным кодом, в том числе 429 и 5xx после исчерпания попыток
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code %d for %s", e.StatusCode, e.URL)
+}
+
+// Is позволяет проверять код ответа через errors.Is(err, ErrNotFound), ErrRateLimited и ErrUpstream
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == 404
+	case ErrRateLimited:
+		return e.StatusCode == 429
+	case ErrUpstream:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// DecodeError - ошибка разбора ответа, исходная ошибка json доступна через errors.As
+type DecodeError struct {
+	URL string
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("failed to decode %s: %v", e.URL, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+func (e *DecodeError) Is(target error) bool {
+	return target == ErrDecode
+}
+
+// DecodeJSON разбирает тело ответа и заворачивает ошибку в DecodeError
+func DecodeJSON(url string, body []byte, v interface{}) error {
+	if err := json.Unmarshal(body, v); err != nil {
+		return &DecodeError{URL: url, Err: err}
+	}
+	return nil
+}
+
+// PartialError - клиент собрал данные не целиком: Part не загрузилась, остальное возвращено вместе с ошибкой.
+// Исходная ошибка доступна через errors.Is/errors.As.
+type PartialError struct {
+	Part string
+	Err  error
+}
+
+func (e *PartialError) Error() string {
+	// Несколько ошибок из errors.Join выводим одной строкой, как и остальные ошибки отчета
+	return fmt.Sprintf("partial data, failed to fetch %s: %s", e.Part, strings.ReplaceAll(e.Err.Error(), "\n", "; "))
+}
+
+func (e *PartialError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,39 @@
+package fetcher
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStatusErrorIs(t *testing.T) {
+	tests := []struct {
+		code                            int
+		notFound, rateLimited, upstream bool
+	}{
+		{code: 404, notFound: true},
+		{code: 429, rateLimited: true},
+		{code: 500, upstream: true},
+		{code: 503, upstream: true},
+		{code: 400},
+		{code: 403},
+	}
+
+	for _, tt := range tests {
+		// Через обертку, как ошибки приходят из клиентов
+		err := &PartialError{Part: "roles", Err: &StatusError{URL: "http://example", StatusCode: tt.code}}
+		if errors.Is(err, ErrNotFound) != tt.notFound ||
+			errors.Is(err, ErrRateLimited) != tt.rateLimited ||
+			errors.Is(err, ErrUpstream) != tt.upstream {
+			t.Errorf("status %d: notFound %v, rateLimited %v, upstream %v", tt.code,
+				errors.Is(err, ErrNotFound), errors.Is(err, ErrRateLimited), errors.Is(err, ErrUpstream))
+		}
+		if errors.Is(err, ErrDecode) {
+			t.Errorf("status %d matches ErrDecode", tt.code)
+		}
+	}
+
+	decodeErr := error(&DecodeError{URL: "http://example", Err: errors.New("bad json")})
+	if !errors.Is(decodeErr, ErrDecode) || errors.Is(decodeErr, ErrUpstream) {
+		t.Errorf("DecodeError mapping is wrong")
+	}
+}
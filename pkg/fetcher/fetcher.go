@@ -1,6 +1,8 @@
 package fetcher
 
 import (
+	"bytes"
+	"context"
 	"dimensi/db-aggregator/pkg/ratelimiter"
 	"fmt"
 	"io"
@@ -10,61 +12,170 @@ import (
 )
 
 type Config struct {
-	MaxRetries      int
-	RetryDelay      time.Duration
-	RetryMultiplier int
-	EnableLogging   bool
+	// Retry решает, какие сбои повторять; nil - одна попытка
+	Retry         RetryPolicy
+	EnableLogging bool
+	Cache         *Cache
+	CacheTTL      time.Duration
 }
 
+// DefaultTimeout - таймаут HTTP-клиента по умолчанию, чтобы одно зависшее соединение не останавливало сборку
+const DefaultTimeout = 30 * time.Second
+
 func DefaultConfig() Config {
 	return Config{
-		MaxRetries:      3,
-		RetryDelay:      time.Second * 10,
-		RetryMultiplier: 2,
-		EnableLogging:   false,
+		Retry:         DefaultRetryPolicy(),
+		EnableLogging: false,
 	}
 }
 
-func FetchWithRetry(client *http.Client, url string, rateLimiter *ratelimiter.RateLimiter, config Config) ([]byte, error) {
+// FetchWithRetry прерывает ожидание лимитера, паузы между попытками и сам запрос при отмене ctx
+func FetchWithRetry(ctx context.Context, client *http.Client, url string, rateLimiter *ratelimiter.RateLimiter, config Config) ([]byte, error) {
+	return doWithRetry(ctx, client, http.MethodGet, url, nil, url, rateLimiter, config)
+}
+
+// PostWithRetry отправляет JSON-тело POST-запросом, например GraphQL-запрос.
+// В кэше ответ хранится по URL вместе с телом запроса.
+func PostWithRetry(ctx context.Context, client *http.Client, url string, body []byte, rateLimiter *ratelimiter.RateLimiter, config Config) ([]byte, error) {
+	return doWithRetry(ctx, client, http.MethodPost, url, body, url+"\n"+string(body), rateLimiter, config)
+}
+
+func doWithRetry(ctx context.Context, client *http.Client, method, url string, reqBody []byte, cacheKey string, rateLimiter *ratelimiter.RateLimiter, config Config) ([]byte, error) {
 	logf := func(format string, v ...interface{}) {
 		if config.EnableLogging {
 			log.Printf(format, v...)
 		}
 	}
 
-	for attempt := 1; attempt <= config.MaxRetries; attempt++ {
-		logf("Fetching URL: %s (attempt %d/%d)", url, attempt, config.MaxRetries)
+	// Свежий ответ из кэша отдаем без обращения к сети, устаревший перепроверяем по ETag/Last-Modified
+	var cached *cacheEntry
+	if config.Cache != nil {
+		if entry, ok := config.Cache.get(cacheKey); ok {
+			if time.Since(entry.FetchedAt) < config.CacheTTL {
+				logf("Cache hit: %s", url)
+				return entry.Body, nil
+			}
+			cached = &entry
+		}
+	}
+
+	// retry ждет перед следующей попыткой; возвращает failure, если повторять не нужно
+	start := time.Now()
+	retry := func(a Attempt, failure error) error {
+		if config.Retry == nil {
+			return failure
+		}
+		delay, ok := config.Retry.Next(a)
+		if !ok {
+			return failure
+		}
+		logf("Attempt %d for %s failed (%v), retrying in %v...", a.Number, url, failure, delay)
+		return sleep(ctx, delay)
+	}
 
-		rateLimiter.Wait()
+	for attempt := 1; ; attempt++ {
+		logf("Fetching URL: %s (attempt %d)", url, attempt)
 
-		resp, err := client.Get(url)
+		var bodyReader io.Reader
+		if reqBody != nil {
+			bodyReader = bytes.NewReader(reqBody)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
 		if err != nil {
-			return nil, fmt.Errorf("failed to fetch URL %s: %v", url, err)
+			return nil, fmt.Errorf("failed to create request for %s: %v", url, err)
+		}
+		if reqBody != nil {
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept", "application/json")
+		}
+		if cached != nil {
+			if cached.ETag != "" {
+				req.Header.Set("If-None-Match", cached.ETag)
+			}
+			if cached.LastModified != "" {
+				req.Header.Set("If-Modified-Since", cached.LastModified)
+			}
 		}
 
-		body, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
+		if err := rateLimiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		body, resp, err := do(client, req)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read response body: %v", err)
+			// Отмена - не сбой источника, повторять нечего
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			if err := retry(Attempt{Number: attempt, Elapsed: time.Since(start), Err: err}, err); err != nil {
+				return nil, err
+			}
+			continue
 		}
 
-		if resp.StatusCode == 429 {
-			if attempt < config.MaxRetries {
-				delay := config.RetryDelay * time.Duration(attempt*config.RetryMultiplier)
-				logf("Rate limit exceeded, waiting %v before retry...", delay)
-				time.Sleep(delay)
-				continue
+		// Лимитер сам замедлится после 429 и подстроится под X-RateLimit-* сервера
+		rateLimiter.Observe(resp.StatusCode, resp.Header)
+
+		if resp.StatusCode == http.StatusNotModified && cached != nil {
+			logf("Not modified: %s", url)
+			cached.FetchedAt = time.Now()
+			if err := config.Cache.put(*cached); err != nil {
+				logf("Failed to update cache for %s: %v", url, err)
 			}
-			return nil, fmt.Errorf("rate limit exceeded after %d attempts", config.MaxRetries)
+			return cached.Body, nil
 		}
 
 		if resp.StatusCode != 200 {
-			return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+			statusErr := &StatusError{URL: url, StatusCode: resp.StatusCode}
+			a := Attempt{Number: attempt, Elapsed: time.Since(start), StatusCode: resp.StatusCode, Header: resp.Header}
+			if err := retry(a, statusErr); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if config.Cache != nil {
+			entry := cacheEntry{
+				URL:          cacheKey,
+				ETag:         resp.Header.Get("ETag"),
+				LastModified: resp.Header.Get("Last-Modified"),
+				FetchedAt:    time.Now(),
+				Body:         body,
+			}
+			if err := config.Cache.put(entry); err != nil {
+				logf("Failed to cache %s: %v", url, err)
+			}
 		}
 
 		logf("Successfully fetched URL: %s", url)
 		return body, nil
 	}
+}
+
+// do выполняет запрос и читает тело целиком, чтобы обрыв посреди ответа тоже считался сетевым сбоем
+func do(client *http.Client, req *http.Request) ([]byte, *http.Response, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch URL %s: %w", req.URL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return body, resp, nil
+}
 
-	return nil, fmt.Errorf("max retries reached")
+// sleep - пауза, которую можно прервать отменой ctx
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
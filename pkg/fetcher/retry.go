@@ -0,0 +1,93 @@
+package fetcher
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"dimensi/db-aggregator/pkg/ratelimiter"
+)
+
+// Attempt - неудачная попытка запроса, по которой политика решает, повторять ли его
+type Attempt struct {
+	// Number - номер попытки, начиная с 1
+	Number int
+	// Elapsed - время с начала первой попытки
+	Elapsed time.Duration
+	// StatusCode и Header пустые, если ответа не было
+	StatusCode int
+	Header     http.Header
+	// Err - сетевая ошибка или ошибка чтения ответа
+	Err error
+}
+
+// Temporary сообщает, что сбой временный: сетевая ошибка, 429 или 500/502/503/504
+func (a Attempt) Temporary() bool {
+	if a.Err != nil {
+		return true
+	}
+	switch a.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// RetryPolicy решает, нужна ли еще одна попытка и сколько перед ней ждать
+type RetryPolicy interface {
+	Next(a Attempt) (time.Duration, bool)
+}
+
+// Backoff повторяет временные сбои с экспоненциальной паузой и случайным разбросом.
+// Retry-After сервера важнее расчетной паузы. Если пауза выходит за MaxElapsed, попытки прекращаются.
+type Backoff struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	// MaxElapsed - общий бюджет времени на все попытки, 0 - без ограничения
+	MaxElapsed time.Duration
+}
+
+// DefaultRetryPolicy подобрана под Jikan: он часто отвечает 429 и 503 на несколько секунд
+func DefaultRetryPolicy() Backoff {
+	return Backoff{
+		MaxAttempts: 5,
+		BaseDelay:   2 * time.Second,
+		MaxDelay:    time.Minute,
+		MaxElapsed:  5 * time.Minute,
+	}
+}
+
+func (b Backoff) Next(a Attempt) (time.Duration, bool) {
+	if a.Number >= b.MaxAttempts || !a.Temporary() {
+		return 0, false
+	}
+
+	delay, ok := ratelimiter.RetryAfter(a.Header)
+	if !ok {
+		delay = b.delay(a.Number)
+	}
+
+	if b.MaxElapsed > 0 && a.Elapsed+delay > b.MaxElapsed {
+		return 0, false
+	}
+	return delay, true
+}
+
+// delay - BaseDelay * 2^(attempt-1), не больше MaxDelay; случайный разброс выбирает паузу из [d/2, d]
+func (b Backoff) delay(attempt int) time.Duration {
+	delay := b.BaseDelay
+	for i := 1; i < attempt && delay < b.MaxDelay; i++ {
+		delay *= 2
+	}
+	if b.MaxDelay > 0 && delay > b.MaxDelay {
+		delay = b.MaxDelay
+	}
+
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + rand.N(half+1)
+}
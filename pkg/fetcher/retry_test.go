@@ -0,0 +1,86 @@
+package fetcher
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBackoffNext(t *testing.T) {
+	policy := Backoff{MaxAttempts: 5, BaseDelay: 2 * time.Second, MaxDelay: time.Minute, MaxElapsed: time.Minute}
+
+	tests := []struct {
+		name    string
+		attempt Attempt
+		retry   bool
+		// min и max - границы паузы, если попытка повторяется
+		min, max time.Duration
+	}{
+		{
+			name:    "network error",
+			attempt: Attempt{Number: 1, Err: errors.New("connection reset")},
+			retry:   true, min: time.Second, max: 2 * time.Second,
+		},
+		{
+			name:    "503 grows exponentially",
+			attempt: Attempt{Number: 3, StatusCode: http.StatusServiceUnavailable},
+			retry:   true, min: 4 * time.Second, max: 8 * time.Second,
+		},
+		{
+			name:    "404 is not retried",
+			attempt: Attempt{Number: 1, StatusCode: http.StatusNotFound},
+			retry:   false,
+		},
+		{
+			name:    "400 is not retried",
+			attempt: Attempt{Number: 1, StatusCode: http.StatusBadRequest},
+			retry:   false,
+		},
+		{
+			name: "retry after overrides computed delay",
+			attempt: Attempt{Number: 1, StatusCode: http.StatusTooManyRequests,
+				Header: http.Header{"Retry-After": {"30"}}},
+			retry: true, min: 30 * time.Second, max: 30 * time.Second,
+		},
+		{
+			name:    "max attempts",
+			attempt: Attempt{Number: 5, StatusCode: http.StatusServiceUnavailable},
+			retry:   false,
+		},
+		{
+			name: "pause beyond max elapsed",
+			attempt: Attempt{Number: 1, Elapsed: 50 * time.Second, StatusCode: http.StatusTooManyRequests,
+				Header: http.Header{"Retry-After": {"20"}}},
+			retry: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, retry := policy.Next(tt.attempt)
+			if retry != tt.retry {
+				t.Fatalf("retry = %v, want %v", retry, tt.retry)
+			}
+			if retry && (delay < tt.min || delay > tt.max) {
+				t.Fatalf("delay %v, want within [%v, %v]", delay, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestBackoffDelayJitter(t *testing.T) {
+	policy := Backoff{BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	for attempt := 1; attempt <= 8; attempt++ {
+		full := time.Second << (attempt - 1)
+		if full > policy.MaxDelay {
+			full = policy.MaxDelay
+		}
+		for i := 0; i < 200; i++ {
+			if d := policy.delay(attempt); d < full/2 || d > full {
+				t.Fatalf("attempt %d: delay %v, want within [%v, %v]", attempt, d, full/2, full)
+			}
+		}
+	}
+}
@@ -1,9 +1,10 @@
 package api
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"net/http"
+	"time"
 
 	"dimensi/db-aggregator/pkg/fetcher"
 	"dimensi/db-aggregator/pkg/jikan"
@@ -11,22 +12,32 @@ import (
 )
 
 type Client struct {
-	httpClient  *http.Client
-	rateLimiter *ratelimiter.RateLimiter
-	config      fetcher.Config
-	baseURL     string
+	fetcher.Client
+	baseURL string
+	// characters - запрашивать ли /characters с актерами озвучки
+	characters bool
 }
 
 func NewClient(httpClient *http.Client, rateLimiter *ratelimiter.RateLimiter) *Client {
 	return &Client{
-		httpClient:  httpClient,
-		rateLimiter: rateLimiter,
-		config:      fetcher.DefaultConfig(),
-		baseURL:     "https://api.jikan.moe/v4/anime",
+		Client:     fetcher.NewClient(httpClient, rateLimiter),
+		baseURL:    "https://api.jikan.moe/v4/anime",
+		characters: true,
 	}
 }
 
-func (c *Client) FetchAnimeData(malID int) (jikan.Data, bool) {
+// SetCharacters включает или выключает запрос персонажей: без ролей Shikimori актеров озвучки не к кому привязать
+func (c *Client) SetCharacters(enabled bool) {
+	c.characters = enabled
+}
+
+func (c *Client) WithCacheTTL(ttl time.Duration) *Client {
+	clone := *c
+	clone.Client = c.Client.WithCacheTTL(ttl)
+	return &clone
+}
+
+func (c *Client) FetchAnimeData(ctx context.Context, malID int) (jikan.Data, error) {
 	var jikanData jikan.Data
 	jikanData.MyAnimeListID = malID
 
@@ -34,10 +45,6 @@ func (c *Client) FetchAnimeData(malID int) (jikan.Data, bool) {
 	page := 1
 	for {
 		url := fmt.Sprintf("%s/%d/episodes?page=%d", c.baseURL, malID, page)
-		body, err := fetcher.FetchWithRetry(c.httpClient, url, c.rateLimiter, c.config)
-		if err != nil {
-			return jikanData, false
-		}
 
 		var response struct {
 			Data       []jikan.Episode `json:"data"`
@@ -45,9 +52,8 @@ func (c *Client) FetchAnimeData(malID int) (jikan.Data, bool) {
 				HasNextPage bool `json:"has_next_page"`
 			} `json:"pagination"`
 		}
-
-		if err := json.Unmarshal(body, &response); err != nil {
-			return jikanData, false
+		if err := c.GetJSON(ctx, url, &response); err != nil {
+			return jikanData, err
 		}
 
 		jikanData.Episodes = append(jikanData.Episodes, response.Data...)
@@ -58,5 +64,21 @@ func (c *Client) FetchAnimeData(malID int) (jikan.Data, bool) {
 		page++
 	}
 
-	return jikanData, true
+	if !c.characters {
+		return jikanData, nil
+	}
+
+	// Получаем персонажей с актерами озвучки; при ошибке эпизоды все равно возвращаем
+	var response struct {
+		Data []jikan.CharacterRole `json:"data"`
+	}
+	if err := c.GetJSON(ctx, fmt.Sprintf("%s/%d/characters", c.baseURL, malID), &response); err != nil {
+		if ctx.Err() != nil {
+			return jikanData, err
+		}
+		return jikanData, &fetcher.PartialError{Part: "characters", Err: err}
+	}
+	jikanData.Characters = response.Data
+
+	return jikanData, nil
 }
@@ -1,9 +1,11 @@
 package jikan
 
+// Characters приходят из /anime/:id/characters, в старых выгрузках jikan-saver их нет
 type Data struct {
-	ID            int       `json:"id"`
-	MyAnimeListID int       `json:"myAnimeListId"`
-	Episodes      []Episode `json:"episodes"`
+	ID            int             `json:"id"`
+	MyAnimeListID int             `json:"myAnimeListId"`
+	Episodes      []Episode       `json:"episodes"`
+	Characters    []CharacterRole `json:"characters,omitempty"`
 }
 
 type Episode struct {
@@ -17,3 +19,32 @@ type Episode struct {
 	Recap         bool    `json:"recap"`
 	ForumURL      string  `json:"forum_url"`
 }
+
+type CharacterRole struct {
+	Character   Character    `json:"character"`
+	Role        string       `json:"role"`
+	VoiceActors []VoiceActor `json:"voice_actors"`
+}
+
+type Character struct {
+	MalID  int    `json:"mal_id"`
+	Name   string `json:"name"`
+	Images Images `json:"images"`
+}
+
+type VoiceActor struct {
+	Person   Person `json:"person"`
+	Language string `json:"language"`
+}
+
+type Person struct {
+	MalID  int    `json:"mal_id"`
+	Name   string `json:"name"`
+	Images Images `json:"images"`
+}
+
+type Images struct {
+	JPG struct {
+		ImageURL string `json:"image_url"`
+	} `json:"jpg"`
+}
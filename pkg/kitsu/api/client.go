@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"dimensi/db-aggregator/pkg/fetcher"
+	"dimensi/db-aggregator/pkg/kitsu"
+	"dimensi/db-aggregator/pkg/ratelimiter"
+)
+
+// pageLimit - максимальный размер страницы, который разрешает Kitsu
+const pageLimit = 20
+
+type Client struct {
+	fetcher.Client
+	baseURL string
+}
+
+func NewClient(httpClient *http.Client, rateLimiter *ratelimiter.RateLimiter) *Client {
+	return &Client{
+		Client:  fetcher.NewClient(httpClient, rateLimiter),
+		baseURL: "https://kitsu.app/api/edge",
+	}
+}
+
+func (c *Client) WithCacheTTL(ttl time.Duration) *Client {
+	clone := *c
+	clone.Client = c.Client.WithCacheTTL(ttl)
+	return &clone
+}
+
+// FetchKitsuID находит Kitsu ID аниме через маппинг myanimelist/anime
+func (c *Client) FetchKitsuID(ctx context.Context, malID int) (int, error) {
+	query := url.Values{}
+	query.Set("filter[externalSite]", "myanimelist/anime")
+	query.Set("filter[externalId]", strconv.Itoa(malID))
+	query.Set("include", "item")
+
+	var response struct {
+		Data []struct {
+			Relationships struct {
+				Item struct {
+					Data struct {
+						Type string `json:"type"`
+						ID   string `json:"id"`
+					} `json:"data"`
+				} `json:"item"`
+			} `json:"relationships"`
+		} `json:"data"`
+	}
+
+	if err := c.GetJSON(ctx, c.baseURL+"/mappings?"+query.Encode(), &response); err != nil {
+		return 0, err
+	}
+
+	for _, mapping := range response.Data {
+		item := mapping.Relationships.Item.Data
+		if item.Type != "anime" {
+			continue
+		}
+		if id, err := strconv.Atoi(item.ID); err == nil {
+			return id, nil
+		}
+	}
+
+	return 0, fmt.Errorf("kitsu mapping for MAL ID %d: %w", malID, fetcher.ErrNotFound)
+}
+
+func (c *Client) FetchAnimeData(ctx context.Context, malID int) (kitsu.Data, error) {
+	var kitsuData kitsu.Data
+	kitsuData.MyAnimeListID = malID
+
+	kitsuID, err := c.FetchKitsuID(ctx, malID)
+	if err != nil {
+		return kitsuData, err
+	}
+	kitsuData.KitsuID = kitsuID
+
+	// Получаем эпизоды постранично, пока есть ссылка на следующую страницу
+	for offset := 0; ; offset += pageLimit {
+		query := url.Values{}
+		query.Set("page[limit]", strconv.Itoa(pageLimit))
+		query.Set("page[offset]", strconv.Itoa(offset))
+		query.Set("sort", "number")
+
+		pageURL := fmt.Sprintf("%s/anime/%d/episodes?%s", c.baseURL, kitsuID, query.Encode())
+
+		var response struct {
+			Data []struct {
+				ID         string        `json:"id"`
+				Attributes kitsu.Episode `json:"attributes"`
+			} `json:"data"`
+			Links struct {
+				Next string `json:"next"`
+			} `json:"links"`
+		}
+
+		if err := c.GetJSON(ctx, pageURL, &response); err != nil {
+			return kitsuData, err
+		}
+
+		for _, item := range response.Data {
+			episode := item.Attributes
+			episode.ID = item.ID
+			kitsuData.Episodes = append(kitsuData.Episodes, episode)
+		}
+
+		if response.Links.Next == "" || len(response.Data) == 0 {
+			break
+		}
+	}
+
+	return kitsuData, nil
+}
@@ -0,0 +1,22 @@
+package kitsu
+
+type Data struct {
+	MyAnimeListID int       `json:"myAnimeListId"`
+	KitsuID       int       `json:"kitsuId"`
+	Episodes      []Episode `json:"episodes"`
+}
+
+// Episode - атрибуты ресурса episodes из JSON:API Kitsu
+type Episode struct {
+	ID             string     `json:"id"`
+	Number         int        `json:"number"`
+	CanonicalTitle string     `json:"canonicalTitle"`
+	Synopsis       string     `json:"synopsis"`
+	Length         int        `json:"length"`
+	Airdate        string     `json:"airdate"`
+	Thumbnail      *Thumbnail `json:"thumbnail"`
+}
+
+type Thumbnail struct {
+	Original string `json:"original"`
+}
@@ -0,0 +1,95 @@
+package ratelimiter
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// defaultWindow - окно лимита сервера, если он сообщил остаток без X-RateLimit-Reset
+const defaultWindow = time.Minute
+
+// Observe подстраивает лимитер под ответ сервера. После 429 лимитер замедляется вдвое
+// и держит паузу по Retry-After; каждый успешный ответ понемногу возвращает исходную скорость.
+// X-RateLimit-Remaining/X-RateLimit-Reset растягивают оставшиеся запросы до сброса лимита.
+func (rl *RateLimiter) Observe(statusCode int, header http.Header) {
+	rl.observe(time.Now(), statusCode, header)
+}
+
+func (rl *RateLimiter) observe(now time.Time, statusCode int, header http.Header) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	// Накопленные токены считаем по старой скорости, до ее изменения
+	rl.refill(now)
+
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		rl.slowdown = math.Min(rl.slowdown*2, maxSlowdown)
+		rl.tokens = math.Min(rl.tokens, 0)
+		if delay, ok := RetryAfter(header); ok {
+			rl.pauseUntil(now.Add(delay))
+		}
+	case statusCode < 400:
+		rl.slowdown = math.Max(1, rl.slowdown*0.9)
+	}
+
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil || remaining < 0 {
+		return
+	}
+	reset, ok := parseReset(header.Get("X-RateLimit-Reset"), now)
+	if !ok {
+		reset = now.Add(defaultWindow)
+	}
+
+	if remaining == 0 {
+		rl.pauseUntil(reset)
+		return
+	}
+	rl.spacing = reset.Sub(now) / time.Duration(remaining)
+	rl.spacingUntil = reset
+}
+
+// pauseUntil останавливает запросы до t, с общим бюджетом - во всех процессах.
+// Ошибку общего файла пропускаем: пауза в своем процессе все равно действует.
+func (rl *RateLimiter) pauseUntil(t time.Time) {
+	if t.After(rl.pausedUntil) {
+		rl.pausedUntil = t
+	}
+	if rl.store != nil {
+		rl.store.Pause(t)
+	}
+}
+
+// RetryAfter понимает Retry-After в секундах и в виде HTTP-даты
+func RetryAfter(header http.Header) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		delay := time.Until(at)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+// parseReset понимает X-RateLimit-Reset и как unix-время (AniList), и как число секунд до сброса
+func parseReset(value string, now time.Time) (time.Time, bool) {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return time.Time{}, false
+	}
+	if n > 1e9 {
+		return time.Unix(n, 0), true
+	}
+	return now.Add(time.Duration(n) * time.Second), true
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package ratelimiter
+
+import "fmt"
+
+func openFileStore(path string) (Store, error) {
+	return nil, fmt.Errorf("shared rate limit file %s is not supported on this platform", path)
+}
@@ -0,0 +1,130 @@
+//go:build unix
+
+package ratelimiter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// fileStore хранит общий бюджет в JSON-файле; каждый доступ идет под flock,
+// так что процессы видят запросы друг друга
+type fileStore struct {
+	file *os.File
+}
+
+type sharedState struct {
+	// Requests - время запросов за последнюю минуту в unix-наносекундах
+	Requests    []int64 `json:"requests"`
+	PausedUntil int64   `json:"pausedUntil"`
+}
+
+func openFileStore(path string) (Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create rate limit directory: %v", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open rate limit file: %v", err)
+	}
+
+	// Проверяем блокировку сразу, чтобы неподходящая файловая система не ломала каждый запрос
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to lock rate limit file %s: %v", path, err)
+	}
+	syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+
+	return &fileStore{file: file}, nil
+}
+
+func (s *fileStore) Reserve(now time.Time, interval time.Duration, perMinute int) (time.Duration, error) {
+	var wait time.Duration
+	err := s.update(func(state *sharedState) bool {
+		longer := func(d time.Duration) {
+			if d > wait {
+				wait = d
+			}
+		}
+
+		// Выкидываем запросы старше минуты
+		i := 0
+		for i < len(state.Requests) && now.Sub(time.Unix(0, state.Requests[i])) >= time.Minute {
+			i++
+		}
+		state.Requests = state.Requests[i:]
+
+		longer(time.Unix(0, state.PausedUntil).Sub(now))
+		if n := len(state.Requests); n > 0 {
+			longer(time.Unix(0, state.Requests[n-1]).Add(interval).Sub(now))
+		}
+		if perMinute > 0 && len(state.Requests) >= perMinute {
+			longer(time.Unix(0, state.Requests[0]).Add(time.Minute).Sub(now))
+		}
+
+		if wait > 0 {
+			return false
+		}
+		state.Requests = append(state.Requests, now.UnixNano())
+		return true
+	})
+	return wait, err
+}
+
+func (s *fileStore) Pause(until time.Time) error {
+	return s.update(func(state *sharedState) bool {
+		if until.UnixNano() <= state.PausedUntil {
+			return false
+		}
+		state.PausedUntil = until.UnixNano()
+		return true
+	})
+}
+
+func (s *fileStore) Close() error {
+	return s.file.Close()
+}
+
+// update читает состояние под блокировкой и записывает его обратно, если fn вернула true
+func (s *fileStore) update(fn func(state *sharedState) bool) error {
+	fd := int(s.file.Fd())
+	if err := syscall.Flock(fd, syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("failed to lock rate limit file: %v", err)
+	}
+	defer syscall.Flock(fd, syscall.LOCK_UN)
+
+	data, err := io.ReadAll(io.NewSectionReader(s.file, 0, 1<<30))
+	if err != nil {
+		return fmt.Errorf("failed to read rate limit file: %v", err)
+	}
+
+	// Испорченный файл (например, после сбоя посреди записи) просто начинаем заново
+	var state sharedState
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &state); err != nil {
+			state = sharedState{}
+		}
+	}
+
+	if !fn(&state) {
+		return nil
+	}
+
+	data, err = json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal rate limit state: %v", err)
+	}
+	if err := s.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to write rate limit file: %v", err)
+	}
+	if _, err := s.file.WriteAt(data, 0); err != nil {
+		return fmt.Errorf("failed to write rate limit file: %v", err)
+	}
+	return nil
+}
@@ -1,37 +1,192 @@
 package ratelimiter
 
 import (
+	"context"
+	"errors"
+	"math"
+	"sync"
 	"time"
 )
 
+// ErrClosed возвращается из Wait после Close
+var ErrClosed = errors.New("rate limiter closed")
+
+// maxSlowdown - во сколько раз лимитер максимум замедляется после серии 429
+const maxSlowdown = 8
+
+// Config - параметры лимитера: ведро на PerSecond запросов в секунду с запасом Burst
+// и скользящее окно на PerMinute запросов за любые 60 секунд
+type Config struct {
+	PerSecond int
+	// PerMinute - 0 без ограничения
+	PerMinute int
+	// Burst - сколько запросов можно сделать подряд без пауз, минимум 1
+	Burst int
+}
+
 type RateLimiter struct {
-	requests chan time.Time
-	ticker   *time.Ticker
+	mu sync.Mutex
+
+	rate     float64
+	burst    float64
+	tokens   float64
+	refilled time.Time
+
+	perMinute int
+	window    []time.Time
+
+	// slowdown делит rate после 429 и постепенно возвращается к 1
+	slowdown float64
+	// pausedUntil - до этого момента запросы не выдаются совсем (Retry-After, исчерпанный лимит)
+	pausedUntil time.Time
+	// spacing - минимальный интервал между запросами по заголовкам сервера, действует до spacingUntil
+	spacing      time.Duration
+	spacingUntil time.Time
+	last         time.Time
+
+	// store - общий с другими процессами бюджет, nil если лимит только свой
+	store Store
+
+	closed    chan struct{}
+	closeOnce sync.Once
 }
 
+// New создает лимитер без запаса: запросы идут не чаще requestsPerSecond в секунду
+// и не больше requestsPerMinute за минуту
 func New(requestsPerSecond, requestsPerMinute int) *RateLimiter {
-	rl := &RateLimiter{
-		requests: make(chan time.Time, requestsPerMinute),
-		ticker:   time.NewTicker(time.Second / time.Duration(requestsPerSecond)),
+	return NewWithConfig(Config{PerSecond: requestsPerSecond, PerMinute: requestsPerMinute, Burst: 1})
+}
+
+func NewWithConfig(cfg Config) *RateLimiter {
+	if cfg.PerSecond < 1 {
+		cfg.PerSecond = 1
+	}
+	if cfg.Burst < 1 {
+		cfg.Burst = 1
+	}
+
+	return &RateLimiter{
+		rate:      float64(cfg.PerSecond),
+		burst:     float64(cfg.Burst),
+		tokens:    float64(cfg.Burst),
+		refilled:  time.Now(),
+		perMinute: cfg.PerMinute,
+		slowdown:  1,
+		closed:    make(chan struct{}),
+	}
+}
+
+// Wait ждет разрешения на запрос; при отмене ctx возвращает ctx.Err() не дожидаясь очереди
+func (rl *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		// Проверяем под блокировкой: Close закрывает общий файл тоже под ней
+		rl.mu.Lock()
+		select {
+		case <-rl.closed:
+			rl.mu.Unlock()
+			return ErrClosed
+		default:
+		}
+		delay, err := rl.reserve(time.Now())
+		rl.mu.Unlock()
+		if err != nil {
+			return err
+		}
+		if delay == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-rl.closed:
+			timer.Stop()
+			return ErrClosed
+		}
+	}
+}
+
+// Close будит всех ожидающих; дальнейшие Wait сразу возвращают ErrClosed
+func (rl *RateLimiter) Close() {
+	rl.closeOnce.Do(func() {
+		close(rl.closed)
+		if rl.store != nil {
+			rl.mu.Lock()
+			rl.store.Close()
+			rl.mu.Unlock()
+		}
+	})
+}
+
+// reserve забирает разрешение и возвращает 0 или сообщает, сколько еще ждать
+func (rl *RateLimiter) reserve(now time.Time) (time.Duration, error) {
+	var wait time.Duration
+	longer := func(d time.Duration) {
+		if d > wait {
+			wait = d
+		}
+	}
+
+	longer(rl.pausedUntil.Sub(now))
+	if now.Before(rl.spacingUntil) {
+		longer(rl.last.Add(rl.spacing).Sub(now))
+	}
+
+	rl.refill(now)
+	if rl.tokens < 1 {
+		seconds := (1 - rl.tokens) / rl.currentRate()
+		longer(time.Duration(math.Ceil(seconds * float64(time.Second))))
 	}
 
-	go func() {
-		minuteTicker := time.NewTicker(time.Minute)
-		for range minuteTicker.C {
-			for len(rl.requests) > 0 {
-				<-rl.requests
-			}
+	if rl.perMinute > 0 {
+		rl.trimWindow(now)
+		if len(rl.window) >= rl.perMinute {
+			longer(rl.window[0].Add(time.Minute).Sub(now))
 		}
-	}()
+	}
+
+	if wait > 0 {
+		return wait, nil
+	}
+
+	// Свой лимит пропускает, осталось занять место в общем бюджете
+	if rl.store != nil {
+		interval := time.Duration(float64(time.Second) / rl.currentRate())
+		wait, err := rl.store.Reserve(now, interval, rl.perMinute)
+		if err != nil || wait > 0 {
+			return wait, err
+		}
+	}
 
-	return rl
+	rl.tokens--
+	rl.last = now
+	if rl.perMinute > 0 {
+		rl.window = append(rl.window, now)
+	}
+	return 0, nil
+}
+
+func (rl *RateLimiter) currentRate() float64 {
+	return rl.rate / rl.slowdown
 }
 
-func (rl *RateLimiter) Wait() {
-	<-rl.ticker.C
-	rl.requests <- time.Now()
+func (rl *RateLimiter) refill(now time.Time) {
+	elapsed := now.Sub(rl.refilled).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	rl.tokens = math.Min(rl.burst, rl.tokens+elapsed*rl.currentRate())
+	rl.refilled = now
+}
 
-	if len(rl.requests) >= cap(rl.requests) {
-		<-rl.requests
+// trimWindow выкидывает запросы старше минуты
+func (rl *RateLimiter) trimWindow(now time.Time) {
+	i := 0
+	for i < len(rl.window) && now.Sub(rl.window[i]) >= time.Minute {
+		i++
 	}
+	rl.window = rl.window[i:]
 }
@@ -0,0 +1,137 @@
+package ratelimiter
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// step - запрос к лимитеру в момент at, или ответ сервера, если status не 0
+type step struct {
+	at     time.Duration
+	status int
+	header http.Header
+	// wait - сколько лимитер велит ждать перед запросом
+	wait time.Duration
+}
+
+func TestReserve(t *testing.T) {
+	t0 := time.Unix(1_700_000_000, 0)
+
+	tests := []struct {
+		name  string
+		cfg   Config
+		steps []step
+	}{
+		{
+			name: "burst",
+			cfg:  Config{PerSecond: 2, Burst: 3},
+			steps: []step{
+				{at: 0},
+				{at: 0},
+				{at: 0},
+				{at: 0, wait: 500 * time.Millisecond},
+				{at: 500 * time.Millisecond},
+				{at: 500 * time.Millisecond, wait: 500 * time.Millisecond},
+			},
+		},
+		{
+			name: "per minute cap",
+			cfg:  Config{PerSecond: 100, PerMinute: 3, Burst: 100},
+			steps: []step{
+				{at: 0},
+				{at: time.Second},
+				{at: 2 * time.Second},
+				{at: 3 * time.Second, wait: 57 * time.Second},
+				{at: 30 * time.Second, wait: 30 * time.Second},
+				{at: time.Minute},
+				{at: time.Minute, wait: time.Second},
+			},
+		},
+		{
+			name: "retry after pause and slowdown",
+			cfg:  Config{PerSecond: 1, Burst: 1},
+			steps: []step{
+				{at: 0},
+				{at: 0, status: http.StatusTooManyRequests, header: http.Header{"Retry-After": {"10"}}},
+				{at: 0, wait: 10 * time.Second},
+				{at: 9 * time.Second, wait: time.Second},
+				{at: 10 * time.Second},
+				// После 429 скорость вдвое ниже: токен копится 2 секунды
+				{at: 10 * time.Second, wait: 2 * time.Second},
+			},
+		},
+		{
+			name: "remaining zero waits for reset",
+			cfg:  Config{PerSecond: 10, Burst: 10},
+			steps: []step{
+				{at: 0, status: http.StatusOK, header: http.Header{
+					"X-Ratelimit-Remaining": {"0"},
+					"X-Ratelimit-Reset":     {"1700000030"},
+				}},
+				{at: 0, wait: 30 * time.Second},
+				{at: 20 * time.Second, wait: 10 * time.Second},
+				{at: 30 * time.Second},
+			},
+		},
+		{
+			name: "remaining spreads requests until reset",
+			cfg:  Config{PerSecond: 100, Burst: 100},
+			steps: []step{
+				{at: 0, status: http.StatusOK, header: http.Header{
+					"X-Ratelimit-Remaining": {"10"},
+					"X-Ratelimit-Reset":     {"20"},
+				}},
+				{at: 0},
+				{at: 0, wait: 2 * time.Second},
+				{at: 2 * time.Second},
+				// После сброса интервал больше не действует
+				{at: 21 * time.Second},
+				{at: 21 * time.Second},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl := NewWithConfig(tt.cfg)
+			rl.refilled = t0
+
+			for i, s := range tt.steps {
+				now := t0.Add(s.at)
+				if s.status != 0 {
+					rl.observe(now, s.status, s.header)
+					continue
+				}
+				wait, err := rl.reserve(now)
+				if err != nil {
+					t.Fatalf("step %d: %v", i, err)
+				}
+				if wait != s.wait {
+					t.Fatalf("step %d at %v: wait %v, want %v", i, s.at, wait, s.wait)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{value: "", ok: false},
+		{value: "5", want: 5 * time.Second, ok: true},
+		{value: "-1", ok: false},
+		{value: "soon", ok: false},
+		{value: "Mon, 02 Jan 2006 15:04:05 GMT", want: 0, ok: true},
+	}
+
+	for _, tt := range tests {
+		got, ok := RetryAfter(http.Header{"Retry-After": {tt.value}})
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("RetryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
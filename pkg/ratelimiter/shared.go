@@ -0,0 +1,39 @@
+package ratelimiter
+
+import (
+	"path/filepath"
+	"time"
+)
+
+// Store - бюджет запросов, общий для нескольких процессов на одной машине
+type Store interface {
+	// Reserve учитывает запрос, если с прошлого запроса любого процесса прошло interval
+	// и за минуту их было меньше perMinute; иначе возвращает, сколько ждать
+	Reserve(now time.Time, interval time.Duration, perMinute int) (time.Duration, error)
+	// Pause останавливает запросы всех процессов до until
+	Pause(until time.Time) error
+	Close() error
+}
+
+// NewShared создает лимитер, который кроме собственных ограничений берет запросы из общего
+// для всех процессов файла path. Процессы, обращающиеся к одному хосту, должны указывать один файл.
+func NewShared(cfg Config, path string) (*RateLimiter, error) {
+	store, err := openFileStore(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rl := NewWithConfig(cfg)
+	rl.store = store
+	return rl, nil
+}
+
+// NewForHost - обычный лимитер, если dir пустой, иначе общий для всех процессов лимитер хоста
+// с файлом dir/<host>.json
+func NewForHost(dir, host string, requestsPerSecond, requestsPerMinute int) (*RateLimiter, error) {
+	if dir == "" {
+		return New(requestsPerSecond, requestsPerMinute), nil
+	}
+	cfg := Config{PerSecond: requestsPerSecond, PerMinute: requestsPerMinute, Burst: 1}
+	return NewShared(cfg, filepath.Join(dir, host+".json"))
+}
@@ -0,0 +1,119 @@
+package saver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"dimensi/db-aggregator/pkg/anime365"
+	"dimensi/db-aggregator/pkg/fetcher"
+	"dimensi/db-aggregator/pkg/ratelimiter"
+)
+
+// FetchFunc загружает данные одного аниме по MAL ID; fetcher.ErrNotFound считается пропуском, а не ошибкой.
+// Данные вместе с fetcher.PartialError записываются как есть.
+type FetchFunc func(ctx context.Context, malID int) (interface{}, error)
+
+// Saver описывает выгрузку одного API по MAL ID из anime365-db.jsonl
+type Saver struct {
+	// Name - название API для сообщений
+	Name string
+	// Output - выходной файл по умолчанию
+	Output string
+	// Host, PerSecond и PerMinute задают лимит запросов
+	Host      string
+	PerSecond int
+	PerMinute int
+	// NewFetch создает клиент API; cache равен nil, если кэш не включен
+	NewFetch func(httpClient *http.Client, limiter *ratelimiter.RateLimiter, cache *fetcher.Cache) FetchFunc
+}
+
+// Main разбирает флаги, загружает все MAL ID и пишет ответы в JSONL, по одной записи на строку.
+// По Ctrl-C останавливается, сохранив уже загруженные записи.
+func Main(s Saver) {
+	// Определяем флаги командной строки
+	inputDir := flag.String("input", ".", "Директория с anime365-db.jsonl")
+	outputPath := flag.String("output", s.Output, "Выходной файл")
+	cacheDir := flag.String("cache-dir", "", "Директория для кэша HTTP-ответов (пусто - без кэша)")
+	rateLimitDir := flag.String("rate-limit-dir", "", "Директория общего лимита запросов для одновременно запущенных программ (пусто - лимит только у этого процесса)")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	malIDs, err := anime365.ReadMALIDs(filepath.Join(*inputDir, "anime365-db.jsonl"))
+	if err != nil {
+		log.Fatalf("Failed to read MAL IDs: %v", err)
+	}
+
+	limiter, err := ratelimiter.NewForHost(*rateLimitDir, s.Host, s.PerSecond, s.PerMinute)
+	if err != nil {
+		log.Fatalf("Failed to create rate limiter: %v", err)
+	}
+	defer limiter.Close()
+
+	var cache *fetcher.Cache
+	if *cacheDir != "" {
+		cache, err = fetcher.NewCache(*cacheDir)
+		if err != nil {
+			log.Fatalf("Failed to open cache: %v", err)
+		}
+	}
+	fetch := s.NewFetch(&http.Client{Timeout: fetcher.DefaultTimeout}, limiter, cache)
+
+	file, err := os.Create(*outputPath)
+	if err != nil {
+		log.Fatalf("Failed to create output file: %v", err)
+	}
+	defer file.Close()
+
+	fmt.Printf("Загружаем %s для %d аниме\n", s.Name, len(malIDs))
+
+	saved, partial, notFound, failed := 0, 0, 0, 0
+	for i, malID := range malIDs {
+		data, err := fetch(ctx, malID)
+		if ctx.Err() != nil {
+			fmt.Printf("\nОстановлено, сохранено: %d\n", saved)
+			return
+		}
+		// Неполные данные сохраняем: без них офлайн-сборка потеряет и то, что загрузилось
+		var partialErr *fetcher.PartialError
+		if errors.As(err, &partialErr) {
+			log.Printf("Incomplete data for MAL ID %d: %v", malID, err)
+			partial++
+			err = nil
+		}
+		if errors.Is(err, fetcher.ErrNotFound) {
+			notFound++
+			continue
+		}
+		if err != nil {
+			log.Printf("Failed to fetch MAL ID %d: %v", malID, err)
+			failed++
+			continue
+		}
+
+		jsonLine, err := json.Marshal(data)
+		if err != nil {
+			log.Printf("Failed to marshal MAL ID %d: %v", malID, err)
+			continue
+		}
+		if _, err := file.WriteString(string(jsonLine) + "\n"); err != nil {
+			log.Fatalf("Failed to write to file: %v", err)
+		}
+		saved++
+
+		fmt.Printf("\rОбработано: %d/%d, сохранено: %d", i+1, len(malIDs), saved)
+	}
+
+	fmt.Printf("\nНеполных: %d, не найдено: %d, ошибок: %d\n", partial, notFound, failed)
+	fmt.Printf("All data successfully saved to %s\n", *outputPath)
+}
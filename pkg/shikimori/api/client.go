@@ -1,9 +1,13 @@
 package api
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"dimensi/db-aggregator/pkg/fetcher"
 	"dimensi/db-aggregator/pkg/ratelimiter"
@@ -11,48 +15,94 @@ import (
 )
 
 type Client struct {
-	httpClient  *http.Client
-	rateLimiter *ratelimiter.RateLimiter
-	config      fetcher.Config
-	baseURL     string
+	fetcher.Client
+	baseURL string
 }
 
 func NewClient(httpClient *http.Client, rateLimiter *ratelimiter.RateLimiter) *Client {
 	return &Client{
-		httpClient:  httpClient,
-		rateLimiter: rateLimiter,
-		config:      fetcher.DefaultConfig(),
-		baseURL:     "https://shikimori.one/api/animes/",
+		Client:  fetcher.NewClient(httpClient, rateLimiter),
+		baseURL: "https://shikimori.one/api/animes/",
 	}
 }
 
-func (c *Client) FetchAnimeData(malID int) (shikimori.Data, bool) {
+func (c *Client) WithCacheTTL(ttl time.Duration) *Client {
+	clone := *c
+	clone.Client = c.Client.WithCacheTTL(ttl)
+	return &clone
+}
+
+// FetchAnimeShow получает только основную карточку аниме без ролей и похожих
+func (c *Client) FetchAnimeShow(ctx context.Context, malID int) (shikimori.AnimeShow, error) {
+	var show shikimori.AnimeShow
+	err := c.GetJSON(ctx, fmt.Sprintf("%s%d", c.baseURL, malID), &show)
+	return show, err
+}
+
+// FetchUpdatedAt получает updated_at нескольких аниме одной страницей списка /api/animes?ids=...
+// Аниме, которых нет в ответе, в результат не попадают.
+func (c *Client) FetchUpdatedAt(ctx context.Context, malIDs []int) (map[int]time.Time, error) {
+	ids := make([]string, 0, len(malIDs))
+	for _, id := range malIDs {
+		ids = append(ids, strconv.Itoa(id))
+	}
+	url := fmt.Sprintf("%s?ids=%s&limit=%d&censored=false", strings.TrimSuffix(c.baseURL, "/"), strings.Join(ids, ","), len(ids))
+
+	var list []struct {
+		ID        int       `json:"id"`
+		UpdatedAt time.Time `json:"updated_at"`
+	}
+	if err := c.GetJSON(ctx, url, &list); err != nil {
+		return nil, err
+	}
+
+	result := make(map[int]time.Time, len(list))
+	for _, item := range list {
+		if !item.UpdatedAt.IsZero() {
+			result[item.ID] = item.UpdatedAt
+		}
+	}
+	return result, nil
+}
+
+// FetchAnimeData собирает карточку, роли, похожие и связанные аниме.
+// Без карточки данных нет, и ошибка возвращается как есть. Если не загрузились роли, похожие
+// или связанные, остальное возвращается вместе с fetcher.PartialError, чтобы ошибка попала в отчет.
+func (c *Client) FetchAnimeData(ctx context.Context, malID int) (shikimori.Data, error) {
 	var shikiData shikimori.Data
+	shikiData.MyAnimeListID = malID
 
 	// Получаем основные данные
-	url := fmt.Sprintf("%s%d", c.baseURL, malID)
-	body, err := fetcher.FetchWithRetry(c.httpClient, url, c.rateLimiter, c.config)
+	show, err := c.FetchAnimeShow(ctx, malID)
 	if err != nil {
-		return shikiData, false
+		return shikiData, err
 	}
+	shikiData.ShikimoriData = show
 
-	if err := json.Unmarshal(body, &shikiData.ShikimoriData); err != nil {
-		return shikiData, false
+	// Роли, похожие и связанные аниме (продолжения, предыстории, спин-оффы)
+	parts := []struct {
+		name string
+		v    interface{}
+	}{
+		{"roles", &shikiData.Roles},
+		{"similar", &shikiData.Similar},
+		{"related", &shikiData.Related},
 	}
 
-	// Получаем роли
-	url = fmt.Sprintf("%s%d/roles", c.baseURL, malID)
-	body, err = fetcher.FetchWithRetry(c.httpClient, url, c.rateLimiter, c.config)
-	if err == nil {
-		json.Unmarshal(body, &shikiData.Roles)
+	var failed []string
+	var errs []error
+	for _, part := range parts {
+		if err := c.GetJSON(ctx, fmt.Sprintf("%s%d/%s", c.baseURL, malID, part.name), part.v); err != nil {
+			if ctx.Err() != nil {
+				return shikiData, err
+			}
+			failed = append(failed, part.name)
+			errs = append(errs, err)
+		}
 	}
-
-	// Получаем похожие аниме
-	url = fmt.Sprintf("%s%d/similar", c.baseURL, malID)
-	body, err = fetcher.FetchWithRetry(c.httpClient, url, c.rateLimiter, c.config)
-	if err == nil {
-		json.Unmarshal(body, &shikiData.Similar)
+	if len(errs) > 0 {
+		return shikiData, &fetcher.PartialError{Part: strings.Join(failed, ", "), Err: errors.Join(errs...)}
 	}
 
-	return shikiData, true
+	return shikiData, nil
 }
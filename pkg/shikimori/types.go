@@ -3,11 +3,57 @@ package shikimori
 import "time"
 
 type Data struct {
-	MyAnimeListID int                      `json:"myAnimeListId"`
-	ShikimoriData AnimeShow                `json:"shikimoriData"`
-	Roles         []map[string]interface{} `json:"roles"`
-	Screenshots   []map[string]interface{} `json:"screenshots"`
-	Similar       []map[string]interface{} `json:"similar"`
+	MyAnimeListID int          `json:"myAnimeListId"`
+	ShikimoriData AnimeShow    `json:"shikimoriData"`
+	Roles         []Role       `json:"roles"`
+	Screenshots   []Screenshot `json:"screenshots"`
+	Similar       []AnimeShort `json:"similar"`
+	Related       []Related    `json:"related"`
+}
+
+// Role - элемент /api/animes/:id/roles: персонаж или человек из стаффа, пустые поля приходят как null
+type Role struct {
+	Roles        []string   `json:"roles"`
+	RolesRussian []string   `json:"roles_russian"`
+	Character    *Character `json:"character"`
+	Person       *Person    `json:"person"`
+}
+
+type Character struct {
+	ID      int64  `json:"id"`
+	Name    string `json:"name"`
+	Russian string `json:"russian"`
+	Image   Image  `json:"image"`
+	URL     string `json:"url"`
+}
+
+type Person struct {
+	ID      int64  `json:"id"`
+	Name    string `json:"name"`
+	Russian string `json:"russian"`
+	Image   Image  `json:"image"`
+	URL     string `json:"url"`
+}
+
+// Related - связь из /api/animes/:id/related; у связей с мангой Anime пустой
+type Related struct {
+	Relation        string      `json:"relation"`
+	RelationRussian string      `json:"relation_russian"`
+	Anime           *AnimeShort `json:"anime"`
+}
+
+// AnimeShort - краткая карточка аниме в /similar и /related
+type AnimeShort struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	Russian    string `json:"russian"`
+	Image      Image  `json:"image"`
+	Kind       string `json:"kind"`
+	Score      string `json:"score"`
+	Status     string `json:"status"`
+	Episodes   int64  `json:"episodes"`
+	AiredOn    string `json:"aired_on"`
+	ReleasedOn string `json:"released_on"`
 }
 
 type AnimeShow struct {
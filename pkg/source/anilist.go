@@ -0,0 +1,98 @@
+package source
+
+import (
+	"context"
+	"strings"
+
+	"dimensi/db-aggregator/pkg/anilist"
+	"dimensi/db-aggregator/pkg/db"
+)
+
+// TagMinRank отсекает теги, которые AniList считает слабо подходящими
+const TagMinRank = 50
+
+// AniListFetcher - клиент AniList API; выгрузки у AniList нет, источник работает только через API
+type AniListFetcher interface {
+	FetchAnimeData(ctx context.Context, malID int) (anilist.Data, error)
+}
+
+type AniList struct {
+	client      AniListFetcher
+	freshClient AniListFetcher
+}
+
+// NewAniList принимает клиент и его копию с нулевым временем жизни кэша (WithCacheTTL(0)):
+// через нее идут запросы с Fresh, чтобы у онгоингов обновлялась следующая серия
+func NewAniList(client, freshClient AniListFetcher) *AniList {
+	return &AniList{client: client, freshClient: freshClient}
+}
+
+func (a *AniList) Name() string {
+	return db.SourceAniList
+}
+
+func (a *AniList) Priority() int {
+	return 30
+}
+
+func (a *AniList) Fetch(ctx context.Context, req Request) (Contribution, error) {
+	if req.IDs.MyAnimeList == 0 {
+		return nil, ErrNoData
+	}
+
+	client := a.client
+	if req.Fresh {
+		client = a.freshClient
+	}
+
+	data, err := client.FetchAnimeData(ctx, req.IDs.MyAnimeList)
+	if err != nil {
+		return nil, err
+	}
+	if data.Media.ID == 0 {
+		return nil, ErrNoData
+	}
+	return aniListContribution(data), nil
+}
+
+type aniListContribution anilist.Data
+
+func (c aniListContribution) Apply(anime *db.Anime) {
+	media := c.Media
+
+	anime.ExternalIDs.AniList = media.ID
+	anime.BannerImage = media.BannerImage
+	anime.Tags = mapTags(media.Tags)
+
+	// AniList отдает nextAiringEpisode только для онгоингов и анонсов
+	anime.NextEpisode = nil
+	if media.NextAiringEpisode != nil {
+		anime.NextEpisode = &db.NextEpisode{
+			Episode:  media.NextAiringEpisode.Episode,
+			AiringAt: media.NextAiringEpisode.AiringAt,
+		}
+	}
+
+	if description := strings.TrimSpace(media.Description); description != "" {
+		anime.Descriptions = append(anime.Descriptions, db.Description{
+			Source: db.SourceAniList,
+			Value:  description,
+		})
+	}
+}
+
+func mapTags(tags []anilist.Tag) []db.Tag {
+	result := make([]db.Tag, 0, len(tags))
+	for _, tag := range tags {
+		if tag.Rank < TagMinRank {
+			continue
+		}
+		result = append(result, db.Tag{
+			Name:     tag.Name,
+			Category: tag.Category,
+			Rank:     tag.Rank,
+			Spoiler:  tag.IsMediaSpoiler,
+		})
+	}
+	return result
+}
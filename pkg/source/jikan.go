@@ -0,0 +1,120 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"dimensi/db-aggregator/pkg/db"
+	"dimensi/db-aggregator/pkg/jikan"
+)
+
+// JikanFetcher реализуют и API-клиент, и выгрузка jikan-saver
+type JikanFetcher interface {
+	FetchAnimeData(ctx context.Context, malID int) (jikan.Data, error)
+}
+
+type Jikan struct {
+	client      JikanFetcher
+	freshClient JikanFetcher
+}
+
+// NewJikan принимает отдельный клиент без кэша для запросов с Fresh
+func NewJikan(client, freshClient JikanFetcher) *Jikan {
+	return &Jikan{client: client, freshClient: freshClient}
+}
+
+func (j *Jikan) Name() string {
+	return db.SourceJikan
+}
+
+// Priority выше, чем у Shikimori: актеров озвучки Jikan привязывает к уже собранным персонажам
+func (j *Jikan) Priority() int {
+	return 25
+}
+
+func (j *Jikan) Fetch(ctx context.Context, req Request) (Contribution, error) {
+	if req.IDs.MyAnimeList == 0 {
+		return nil, ErrNoData
+	}
+
+	client := j.client
+	if req.Fresh {
+		client = j.freshClient
+	}
+
+	data, err := client.FetchAnimeData(ctx, req.IDs.MyAnimeList)
+	if err != nil && !partialData(err) {
+		return nil, err
+	}
+	if len(data.Episodes) == 0 && len(data.Characters) == 0 {
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrNoData
+	}
+	return jikanContribution(data), err
+}
+
+type jikanContribution jikan.Data
+
+// Apply дополняет эпизоды anime365 данными Jikan, сопоставляя их по номеру,
+// и добавляет персонажам актеров озвучки; ID персонажей MAL и Shikimori совпадают
+func (c jikanContribution) Apply(anime *db.Anime) {
+	c.applyEpisodes(anime)
+	c.applySeiyuu(anime)
+}
+
+func (c jikanContribution) applyEpisodes(anime *db.Anime) {
+	byNumber := make(map[int]jikan.Episode, len(c.Episodes))
+	for _, ep := range c.Episodes {
+		if _, ok := byNumber[ep.MalID]; !ok {
+			byNumber[ep.MalID] = ep
+		}
+	}
+
+	for i := range anime.Episodes {
+		ep := &anime.Episodes[i]
+
+		jikanEp, ok := byNumber[ep.Number]
+		if !ok || ep.Number == 0 {
+			continue
+		}
+
+		ep.AirDate = jikanEp.Aired
+		ep.Titles = map[string]string{
+			"en":     strings.TrimSpace(jikanEp.Title),
+			"ja":     strings.TrimSpace(jikanEp.TitleJapanese),
+			"romaji": strings.TrimSpace(jikanEp.TitleRomanji),
+		}
+		ep.Rating = fmt.Sprintf("%.2f", jikanEp.Score)
+	}
+}
+
+func (c jikanContribution) applySeiyuu(anime *db.Anime) {
+	byCharacter := make(map[int][]jikan.VoiceActor, len(c.Characters))
+	for _, ch := range c.Characters {
+		byCharacter[ch.Character.MalID] = ch.VoiceActors
+	}
+
+	for i := range anime.Roles {
+		role := &anime.Roles[i]
+
+		actors, ok := byCharacter[role.Character.ID]
+		if !ok || len(actors) == 0 {
+			continue
+		}
+
+		role.Seiyuu = make([]db.Seiyuu, 0, len(actors))
+		for _, actor := range actors {
+			role.Seiyuu = append(role.Seiyuu, db.Seiyuu{
+				Person: db.Person{
+					ID:    actor.Person.MalID,
+					Name:  strings.TrimSpace(actor.Person.Name),
+					Image: db.Image{Original: actor.Person.Images.JPG.ImageURL},
+				},
+				Language: actor.Language,
+			})
+		}
+	}
+}
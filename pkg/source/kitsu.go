@@ -0,0 +1,83 @@
+package source
+
+import (
+	"context"
+	"strings"
+
+	"dimensi/db-aggregator/pkg/db"
+	"dimensi/db-aggregator/pkg/kitsu"
+)
+
+// KitsuFetcher - клиент Kitsu API; выгрузки у Kitsu нет, источник работает только через API
+type KitsuFetcher interface {
+	FetchAnimeData(ctx context.Context, malID int) (kitsu.Data, error)
+}
+
+type Kitsu struct {
+	client      KitsuFetcher
+	freshClient KitsuFetcher
+}
+
+// NewKitsu принимает клиент и его копию с нулевым временем жизни кэша (WithCacheTTL(0)):
+// через нее идут запросы с Fresh, чтобы у онгоингов появлялись новые эпизоды
+func NewKitsu(client, freshClient KitsuFetcher) *Kitsu {
+	return &Kitsu{client: client, freshClient: freshClient}
+}
+
+func (k *Kitsu) Name() string {
+	return db.SourceKitsu
+}
+
+func (k *Kitsu) Priority() int {
+	return 40
+}
+
+func (k *Kitsu) Fetch(ctx context.Context, req Request) (Contribution, error) {
+	if req.IDs.MyAnimeList == 0 {
+		return nil, ErrNoData
+	}
+
+	client := k.client
+	if req.Fresh {
+		client = k.freshClient
+	}
+
+	data, err := client.FetchAnimeData(ctx, req.IDs.MyAnimeList)
+	if err != nil {
+		return nil, err
+	}
+	// Без эпизодов вклад все равно дает Kitsu ID
+	if data.KitsuID == 0 && len(data.Episodes) == 0 {
+		return nil, ErrNoData
+	}
+	return kitsuContribution(data), nil
+}
+
+type kitsuContribution kitsu.Data
+
+// Apply добавляет к эпизодам anime365 превью, описание и длительность, сопоставляя их по номеру
+func (c kitsuContribution) Apply(anime *db.Anime) {
+	anime.ExternalIDs.Kitsu = c.KitsuID
+
+	byNumber := make(map[int]kitsu.Episode, len(c.Episodes))
+	for _, ep := range c.Episodes {
+		if _, ok := byNumber[ep.Number]; !ok && ep.Number != 0 {
+			byNumber[ep.Number] = ep
+		}
+	}
+
+	for i := range anime.Episodes {
+		ep := &anime.Episodes[i]
+
+		kitsuEp, ok := byNumber[ep.Number]
+		if !ok || ep.Number == 0 {
+			continue
+		}
+
+		if kitsuEp.Thumbnail != nil {
+			ep.Thumbnail = kitsuEp.Thumbnail.Original
+		}
+		ep.Synopsis = strings.TrimSpace(kitsuEp.Synopsis)
+		ep.Length = kitsuEp.Length
+	}
+}
@@ -0,0 +1,362 @@
+package source
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"dimensi/db-aggregator/pkg/db"
+	"dimensi/db-aggregator/pkg/shikimori"
+)
+
+const (
+	ScreenshotsLimit = 5
+	RolesLimit       = 5
+	SimilarLimit     = 5
+)
+
+// ShikimoriFetcher реализуют и API-клиент, и выгрузка shikimori-saver
+type ShikimoriFetcher interface {
+	FetchAnimeData(ctx context.Context, malID int) (shikimori.Data, error)
+	// FetchUpdatedAt получает updated_at сразу нескольких аниме, не больше UpdatedAtBatch за вызов
+	FetchUpdatedAt(ctx context.Context, malIDs []int) (map[int]time.Time, error)
+}
+
+// UpdatedAtBatch - сколько аниме Shikimori отдает одной страницей списка
+const UpdatedAtBatch = 50
+
+// DefaultRoles - роли, которые попадают в базу, если фильтр не задан
+const DefaultRoles = "Main"
+
+// RoleFilter - роли Shikimori в нижнем регистре: main, supporting и должности стаффа (director, music...).
+// "*" пропускает все роли.
+type RoleFilter map[string]bool
+
+// ParseRoleFilter разбирает список ролей через запятую
+func ParseRoleFilter(roles string) RoleFilter {
+	filter := make(RoleFilter)
+	for _, role := range strings.Split(roles, ",") {
+		if role = strings.ToLower(strings.TrimSpace(role)); role != "" {
+			filter[role] = true
+		}
+	}
+	return filter
+}
+
+// String возвращает фильтр в каноническом виде, чтобы сравнивать настройки разных сборок
+func (f RoleFilter) String() string {
+	roles := make([]string, 0, len(f))
+	for role := range f {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return strings.Join(roles, ",")
+}
+
+// Match сообщает, есть ли среди ролей записи хотя бы одна разрешенная
+func (f RoleFilter) Match(roles []string) bool {
+	if f["*"] {
+		return true
+	}
+	for _, role := range roles {
+		if f[strings.ToLower(role)] {
+			return true
+		}
+	}
+	return false
+}
+
+type Shikimori struct {
+	client      ShikimoriFetcher
+	freshClient ShikimoriFetcher
+	roles       RoleFilter
+}
+
+// NewShikimori принимает отдельный клиент без кэша для запросов с Fresh
+func NewShikimori(client, freshClient ShikimoriFetcher) *Shikimori {
+	return &Shikimori{client: client, freshClient: freshClient, roles: ParseRoleFilter(DefaultRoles)}
+}
+
+// SetRoleFilter задает, каких персонажей и кого из стаффа сохранять
+func (s *Shikimori) SetRoleFilter(filter RoleFilter) {
+	s.roles = filter
+}
+
+func (s *Shikimori) Name() string {
+	return db.SourceShikimori
+}
+
+func (s *Shikimori) Priority() int {
+	return 20
+}
+
+func (s *Shikimori) Fetch(ctx context.Context, req Request) (Contribution, error) {
+	if req.IDs.MyAnimeList == 0 {
+		return nil, ErrNoData
+	}
+
+	client := s.client
+	if req.Fresh {
+		client = s.freshClient
+	}
+
+	data, err := client.FetchAnimeData(ctx, req.IDs.MyAnimeList)
+	if err != nil && !partialData(err) {
+		return nil, err
+	}
+	return shikimoriContribution{data: data, roles: s.roles}, err
+}
+
+// UpdatedAt возвращает текущий updated_at аниме в обход кэша, чтобы заметить изменения на Shikimori.
+// Запросы идут пачками по UpdatedAtBatch; аниме из пачки, которая не загрузилась, в ответ не попадают,
+// а ошибка последней такой пачки возвращается вместе с остальными данными.
+func (s *Shikimori) UpdatedAt(ctx context.Context, malIDs []int) (map[int]string, error) {
+	result := make(map[int]string, len(malIDs))
+	var lastErr error
+
+	for start := 0; start < len(malIDs); start += UpdatedAtBatch {
+		end := min(start+UpdatedAtBatch, len(malIDs))
+		batch, err := s.freshClient.FetchUpdatedAt(ctx, malIDs[start:end])
+		if err != nil {
+			if ctx.Err() != nil {
+				return result, err
+			}
+			lastErr = err
+			continue
+		}
+		for malID, updatedAt := range batch {
+			result[malID] = FormatUpdatedAt(updatedAt)
+		}
+	}
+
+	return result, lastErr
+}
+
+type shikimoriContribution struct {
+	data  shikimori.Data
+	roles RoleFilter
+}
+
+func (c shikimoriContribution) Apply(anime *db.Anime) {
+	shiki := c.data
+
+	anime.ExternalIDs.Shikimori = int(shiki.ShikimoriData.ID)
+	anime.AiredOn = shiki.ShikimoriData.AiredOn
+	anime.ReleasedOn = shiki.ShikimoriData.ReleasedOn
+	anime.ShikimoriUpdatedAt = FormatUpdatedAt(shiki.ShikimoriData.UpdatedAt)
+	anime.Duration = int(shiki.ShikimoriData.Duration)
+	anime.Roles = mapRoles(shiki.Roles, c.roles)
+	anime.Staff = mapStaff(shiki.Roles, c.roles)
+	anime.Screenshots = mapScreenshotsBase(shiki.ShikimoriData.Screenshots)
+	// anime.Screenshots = mapScreenshots(shiki.Screenshots, ScreenshotsLimit)
+	anime.Similar = mapSimilar(shiki.Similar, SimilarLimit)
+	anime.Relations = mapRelations(shiki.Related)
+	anime.Franchise = shiki.ShikimoriData.Franchise
+	anime.Studios = mapStudios(shiki.ShikimoriData.Studios)
+	anime.Trailers = mapTrailers(shiki.ShikimoriData.Videos)
+
+	// Маппинг постера
+	anime.Poster.Shikimori = prefixImage(shiki.ShikimoriData.Image)
+}
+
+// FormatUpdatedAt приводит updated_at Shikimori к виду, который хранится в db.Anime
+func FormatUpdatedAt(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func mapStudios(studios []shikimori.Studio) []db.Studio {
+	result := make([]db.Studio, 0, len(studios))
+
+	for _, s := range studios {
+		result = append(result, db.Studio{
+			ID:           s.ID,
+			FilteredName: s.FilteredName,
+			Image:        s.Image,
+			Name:         s.Name,
+			Real:         s.Real,
+		})
+	}
+
+	return result
+}
+
+func mapRoles(roles []shikimori.Role, filter RoleFilter) []db.Role {
+	result := make([]db.Role, 0, len(roles))
+
+	for _, r := range roles {
+		// Стафф приходит без персонажа, он попадает в mapStaff
+		if r.Character == nil || !filter.Match(r.Roles) {
+			continue
+		}
+
+		result = append(result, db.Role{
+			Character: db.Character{
+				ID:      int(r.Character.ID),
+				Name:    strings.TrimSpace(r.Character.Name),
+				Russian: strings.TrimSpace(r.Character.Russian),
+				Image:   prefixImage(r.Character.Image),
+			},
+			RoleNames: mapRoleNames(r),
+		})
+	}
+
+	return result
+}
+
+func mapStaff(roles []shikimori.Role, filter RoleFilter) []db.Staff {
+	result := make([]db.Staff, 0)
+
+	for _, r := range roles {
+		if r.Person == nil || !filter.Match(r.Roles) {
+			continue
+		}
+
+		result = append(result, db.Staff{
+			Person: db.Person{
+				ID:      int(r.Person.ID),
+				Name:    strings.TrimSpace(r.Person.Name),
+				Russian: strings.TrimSpace(r.Person.Russian),
+				Image:   prefixImage(r.Person.Image),
+			},
+			RoleNames: mapRoleNames(r),
+		})
+	}
+
+	return result
+}
+
+// mapRoleNames склеивает роли с русскими названиями, они идут в том же порядке
+func mapRoleNames(r shikimori.Role) []db.RoleName {
+	result := make([]db.RoleName, 0, len(r.Roles))
+	for i, name := range r.Roles {
+		roleName := db.RoleName{Name: name}
+		if i < len(r.RolesRussian) {
+			roleName.Russian = r.RolesRussian[i]
+		}
+		result = append(result, roleName)
+	}
+	return result
+}
+
+func mapTrailers(trailers []shikimori.Video) []db.Video {
+	result := make([]db.Video, 0, len(trailers))
+
+	for _, t := range trailers {
+		result = append(result, db.Video{
+			Hosting:   t.Hosting,
+			ID:        t.ID,
+			ImageURL:  t.ImageURL,
+			Kind:      t.Kind,
+			Name:      t.Name,
+			PlayerURL: t.PlayerURL,
+			URL:       t.URL,
+		})
+	}
+
+	return result
+}
+
+func mapScreenshotsBase(screenshots []shikimori.Screenshot) []db.Screenshot {
+	result := make([]db.Screenshot, 0, len(screenshots))
+
+	for _, s := range screenshots {
+		result = append(result, db.Screenshot{
+			Original: s.Original,
+			Preview:  s.Preview,
+		})
+	}
+
+	return result
+}
+
+// func mapScreenshots(screenshots []shikimori.Screenshot, limit int) []db.Screenshot {
+// 	result := make([]db.Screenshot, 0, limit)
+
+// 	for _, s := range screenshots {
+// 		result = append(result, db.Screenshot{
+// 			Original: s.Original,
+// 			Preview:  s.Preview,
+// 		})
+
+// 		if len(result) >= limit {
+// 			break
+// 		}
+// 	}
+
+// 	return result
+// }
+
+func mapSimilar(similar []shikimori.AnimeShort, limit int) []db.Similar {
+	result := make([]db.Similar, 0, limit)
+
+	for _, s := range similar {
+		result = append(result, db.Similar{
+			MyAnimeListID: int(s.ID),
+			Titles: map[string]string{
+				"en": strings.TrimSpace(s.Name),
+				"ru": strings.TrimSpace(s.Russian),
+			},
+			Score: strings.TrimSpace(s.Score),
+			Image: prefixImage(s.Image),
+		})
+
+		if len(result) >= limit {
+			break
+		}
+	}
+
+	return result
+}
+
+// mapRelations оставляет только связи с аниме, связи с мангой и ранобэ пропускаются
+func mapRelations(related []shikimori.Related) []db.Relation {
+	result := make([]db.Relation, 0, len(related))
+
+	for _, r := range related {
+		if r.Anime == nil {
+			continue
+		}
+
+		result = append(result, db.Relation{
+			Relation:        relationKind(r.Relation),
+			RelationRussian: strings.TrimSpace(r.RelationRussian),
+			MyAnimeListID:   int(r.Anime.ID),
+			Titles: map[string]string{
+				"en": strings.TrimSpace(r.Anime.Name),
+				"ru": strings.TrimSpace(r.Anime.Russian),
+			},
+			Kind:    r.Anime.Kind,
+			AiredOn: r.Anime.AiredOn,
+			Image:   prefixImage(r.Anime.Image),
+		})
+	}
+
+	return result
+}
+
+// relationKind приводит "Side Story" и "Spin-off" к side_story и spin_off
+func relationKind(relation string) string {
+	relation = strings.ToLower(strings.TrimSpace(relation))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(relation)
+}
+
+// prefixImage превращает относительные пути картинок Shikimori в полные ссылки, пустые пути остаются пустыми
+func prefixImage(img shikimori.Image) db.Image {
+	return db.Image{
+		Original: shikimoriURL(img.Original),
+		Preview:  shikimoriURL(img.Preview),
+		X48:      shikimoriURL(img.X48),
+		X96:      shikimoriURL(img.X96),
+	}
+}
+
+func shikimoriURL(path string) string {
+	if path == "" {
+		return ""
+	}
+	return "https://shikimori.one" + path
+}
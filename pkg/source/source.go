@@ -0,0 +1,55 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"dimensi/db-aggregator/pkg/db"
+	"dimensi/db-aggregator/pkg/fetcher"
+)
+
+// ErrNoData означает, что источник ничего не знает об этом аниме
+var ErrNoData = errors.New("no data")
+
+// IDs - идентификаторы, по которым источник может найти аниме
+type IDs struct {
+	Anime365    int
+	MyAnimeList int
+	AniDB       int
+}
+
+type Request struct {
+	IDs IDs
+	// Fresh просит не доверять кэшу ответов, например для онгоингов
+	Fresh bool
+}
+
+// Contribution - данные одного источника по одному аниме
+type Contribution interface {
+	Apply(anime *db.Anime)
+}
+
+// Source - поставщик дополнительных данных для db.Anime.
+// Fetch возвращает ErrNoData, если источнику нечего добавить, и ошибку клиента, если запрос не удался.
+// Если клиент загрузил данные не целиком (fetcher.PartialError), Fetch возвращает и вклад, и ошибку.
+// Вклады применяются по возрастанию Priority, поэтому источник с большим приоритетом
+// перезаписывает поля, заполненные менее приоритетными.
+type Source interface {
+	Name() string
+	Priority() int
+	Fetch(ctx context.Context, req Request) (Contribution, error)
+}
+
+// partialData сообщает, что вместе с ошибкой клиент вернул пригодные данные
+func partialData(err error) bool {
+	var partial *fetcher.PartialError
+	return errors.As(err, &partial)
+}
+
+// SortByPriority упорядочивает источники в порядке применения вкладов
+func SortByPriority(sources []Source) {
+	sort.SliceStable(sources, func(i, j int) bool {
+		return sources[i].Priority() < sources[j].Priority()
+	})
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"dimensi/db-aggregator/pkg/fetcher"
+	"dimensi/db-aggregator/pkg/ratelimiter"
+	"dimensi/db-aggregator/pkg/saver"
+	shikiapi "dimensi/db-aggregator/pkg/shikimori/api"
+)
+
+func main() {
+	saver.Main(saver.Saver{
+		Name:      "Shikimori",
+		Output:    "shikimori-db.jsonl",
+		Host:      "shikimori.one",
+		PerSecond: 3,
+		PerMinute: 70,
+		NewFetch: func(httpClient *http.Client, limiter *ratelimiter.RateLimiter, cache *fetcher.Cache) saver.FetchFunc {
+			client := shikiapi.NewClient(httpClient, limiter)
+			if cache != nil {
+				client.SetCache(cache, 7*24*time.Hour)
+			}
+			return func(ctx context.Context, malID int) (interface{}, error) {
+				return client.FetchAnimeData(ctx, malID)
+			}
+		},
+	})
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"dimensi/db-aggregator/pkg/db"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var snapshotRegexp = regexp.MustCompile(`^db_(\d+)\.jsonl$`)
+
+const schema = `
+CREATE TABLE meta (
+	key   TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+
+CREATE TABLE anime (
+	id                        INTEGER PRIMARY KEY,
+	mal_id                    INTEGER NOT NULL,
+	score                     TEXT,
+	type                      TEXT,
+	type_title                TEXT,
+	year                      INTEGER,
+	season                    TEXT,
+	number_of_episodes        INTEGER,
+	duration                  INTEGER,
+	is_airing                 INTEGER,
+	aired_on                  TEXT,
+	released_on               TEXT,
+	shikimori_updated_at      TEXT,
+	poster_anime365_original  TEXT,
+	poster_anime365_preview   TEXT,
+	poster_shikimori_original TEXT,
+	poster_shikimori_preview  TEXT,
+	franchise                 TEXT,
+	banner_image              TEXT,
+	next_episode              INTEGER,
+	next_episode_airing_at    INTEGER
+);
+CREATE INDEX anime_mal_id ON anime (mal_id);
+CREATE INDEX anime_year_season ON anime (year, season);
+CREATE INDEX anime_franchise ON anime (franchise);
+
+CREATE TABLE external_ids (
+	anime_id    INTEGER NOT NULL REFERENCES anime (id),
+	source      TEXT NOT NULL,
+	external_id INTEGER NOT NULL,
+	PRIMARY KEY (anime_id, source)
+);
+CREATE INDEX external_ids_source_id ON external_ids (source, external_id);
+
+CREATE TABLE titles (
+	anime_id INTEGER NOT NULL REFERENCES anime (id),
+	lang     TEXT NOT NULL,
+	title    TEXT NOT NULL
+);
+CREATE INDEX titles_anime_id ON titles (anime_id);
+
+CREATE VIRTUAL TABLE titles_fts USING fts5 (
+	title,
+	anime_id UNINDEXED,
+	lang UNINDEXED,
+	tokenize = 'unicode61 remove_diacritics 2'
+);
+
+CREATE TABLE descriptions (
+	anime_id          INTEGER NOT NULL REFERENCES anime (id),
+	source            TEXT,
+	updated_date_time TEXT,
+	value             TEXT
+);
+CREATE INDEX descriptions_anime_id ON descriptions (anime_id);
+
+CREATE TABLE episodes (
+	id                       INTEGER,
+	anime_id                 INTEGER NOT NULL REFERENCES anime (id),
+	number                   INTEGER,
+	type                     TEXT,
+	title                    TEXT,
+	first_uploaded_date_time TEXT,
+	is_active                INTEGER,
+	series_id                INTEGER,
+	is_first_uploaded        INTEGER,
+	air_date                 TEXT,
+	title_en                 TEXT,
+	title_ja                 TEXT,
+	title_romaji             TEXT,
+	rating                   TEXT,
+	thumbnail                TEXT,
+	synopsis                 TEXT,
+	length                   INTEGER
+);
+CREATE INDEX episodes_anime_id ON episodes (anime_id);
+
+CREATE TABLE genres (
+	id    INTEGER PRIMARY KEY,
+	title TEXT,
+	url   TEXT
+);
+
+CREATE TABLE anime_genres (
+	anime_id INTEGER NOT NULL REFERENCES anime (id),
+	genre_id INTEGER NOT NULL REFERENCES genres (id),
+	PRIMARY KEY (anime_id, genre_id)
+);
+CREATE INDEX anime_genres_genre_id ON anime_genres (genre_id);
+
+CREATE TABLE studios (
+	id            INTEGER PRIMARY KEY,
+	name          TEXT,
+	filtered_name TEXT,
+	image         TEXT,
+	real          INTEGER
+);
+
+CREATE TABLE tags (
+	anime_id INTEGER NOT NULL REFERENCES anime (id),
+	name     TEXT NOT NULL,
+	category TEXT,
+	rank     INTEGER,
+	spoiler  INTEGER
+);
+CREATE INDEX tags_anime_id ON tags (anime_id);
+CREATE INDEX tags_name ON tags (name);
+
+CREATE TABLE anime_studios (
+	anime_id  INTEGER NOT NULL REFERENCES anime (id),
+	studio_id INTEGER NOT NULL REFERENCES studios (id),
+	PRIMARY KEY (anime_id, studio_id)
+);
+CREATE INDEX anime_studios_studio_id ON anime_studios (studio_id);
+
+CREATE TABLE characters (
+	id             INTEGER PRIMARY KEY,
+	name           TEXT,
+	russian        TEXT,
+	image_original TEXT,
+	image_preview  TEXT,
+	image_x48      TEXT,
+	image_x96      TEXT
+);
+
+CREATE TABLE roles (
+	anime_id     INTEGER NOT NULL REFERENCES anime (id),
+	character_id INTEGER NOT NULL REFERENCES characters (id),
+	name         TEXT,
+	russian      TEXT
+);
+CREATE INDEX roles_anime_id ON roles (anime_id);
+CREATE INDEX roles_character_id ON roles (character_id);
+
+CREATE TABLE persons (
+	id             INTEGER PRIMARY KEY,
+	name           TEXT,
+	russian        TEXT,
+	image_original TEXT,
+	image_preview  TEXT
+);
+
+CREATE TABLE seiyuu (
+	anime_id     INTEGER NOT NULL REFERENCES anime (id),
+	character_id INTEGER NOT NULL REFERENCES characters (id),
+	person_id    INTEGER NOT NULL REFERENCES persons (id),
+	language     TEXT
+);
+CREATE INDEX seiyuu_anime_id ON seiyuu (anime_id);
+CREATE INDEX seiyuu_person_id ON seiyuu (person_id);
+
+CREATE TABLE staff (
+	anime_id  INTEGER NOT NULL REFERENCES anime (id),
+	person_id INTEGER NOT NULL REFERENCES persons (id),
+	name      TEXT,
+	russian   TEXT
+);
+CREATE INDEX staff_anime_id ON staff (anime_id);
+CREATE INDEX staff_person_id ON staff (person_id);
+
+CREATE TABLE screenshots (
+	anime_id INTEGER NOT NULL REFERENCES anime (id),
+	position INTEGER NOT NULL,
+	original TEXT,
+	preview  TEXT
+);
+CREATE INDEX screenshots_anime_id ON screenshots (anime_id);
+
+CREATE TABLE similar (
+	anime_id       INTEGER NOT NULL REFERENCES anime (id),
+	position       INTEGER NOT NULL,
+	mal_id         INTEGER,
+	title_en       TEXT,
+	title_ru       TEXT,
+	score          TEXT,
+	image_original TEXT,
+	image_preview  TEXT
+);
+CREATE INDEX similar_anime_id ON similar (anime_id);
+
+CREATE TABLE relations (
+	anime_id         INTEGER NOT NULL REFERENCES anime (id),
+	relation         TEXT NOT NULL,
+	relation_russian TEXT,
+	mal_id           INTEGER,
+	title_en         TEXT,
+	title_ru         TEXT,
+	kind             TEXT,
+	aired_on         TEXT,
+	image_original   TEXT,
+	image_preview    TEXT
+);
+CREATE INDEX relations_anime_id ON relations (anime_id);
+CREATE INDEX relations_mal_id ON relations (mal_id);
+
+CREATE TABLE trailers (
+	anime_id   INTEGER NOT NULL REFERENCES anime (id),
+	id         INTEGER,
+	hosting    TEXT,
+	kind       TEXT,
+	name       TEXT,
+	url        TEXT,
+	player_url TEXT,
+	image_url  TEXT
+);
+CREATE INDEX trailers_anime_id ON trailers (anime_id);
+`
+
+func main() {
+	// Определяем флаги командной строки
+	dbDir := flag.String("db-dir", ".", "Директория со снимками db_*.jsonl")
+	input := flag.String("input", "", "Снимок для экспорта (по умолчанию самый новый в -db-dir)")
+	flag.Parse()
+
+	inputPath := *input
+	if inputPath == "" {
+		latest, err := findLatestSnapshot(*dbDir)
+		if err != nil {
+			log.Fatalf("Failed to find snapshot: %v", err)
+		}
+		inputPath = latest
+	}
+
+	outputPath := strings.TrimSuffix(inputPath, ".jsonl") + ".sqlite"
+	fmt.Printf("Экспортируем %s в %s\n", inputPath, outputPath)
+
+	count, err := export(inputPath, outputPath)
+	if err != nil {
+		log.Fatalf("Failed to export: %v", err)
+	}
+
+	fmt.Printf("Экспортировано аниме: %d\n", count)
+}
+
+func findLatestSnapshot(dir string) (string, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read directory: %v", err)
+	}
+
+	names := make([]string, 0)
+	for _, file := range files {
+		if snapshotRegexp.MatchString(file.Name()) {
+			names = append(names, file.Name())
+		}
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("no db_*.jsonl files in %s", dir)
+	}
+
+	// Timestamp одной длины, поэтому строковая сортировка совпадает с числовой
+	sort.Strings(names)
+	return filepath.Join(dir, names[len(names)-1]), nil
+}
+
+// export пишет во временный файл и переименовывает его, чтобы db-server не отдал недописанную базу
+func export(inputPath, outputPath string) (int, error) {
+	input, err := os.Open(inputPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open snapshot: %v", err)
+	}
+	defer input.Close()
+
+	tmpPath := outputPath + ".tmp"
+	os.Remove(tmpPath)
+	defer os.Remove(tmpPath)
+
+	conn, err := sql.Open("sqlite3", tmpPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open sqlite: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Exec(schema); err != nil {
+		if strings.Contains(err.Error(), "fts5") {
+			return 0, fmt.Errorf("sqlite built without FTS5, rebuild with -tags sqlite_fts5: %v", err)
+		}
+		return 0, fmt.Errorf("failed to create schema: %v", err)
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	w, err := newWriter(tx)
+	if err != nil {
+		return 0, err
+	}
+	defer w.close()
+
+	if err := w.writeMeta(filepath.Base(inputPath)); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	scanner := bufio.NewScanner(input)
+	buf := make([]byte, 10*1024*1024)
+	scanner.Buffer(buf, 10*1024*1024)
+	for scanner.Scan() {
+		var anime db.Anime
+		if err := json.Unmarshal(scanner.Bytes(), &anime); err != nil {
+			log.Printf("Failed to parse record: %v", err)
+			continue
+		}
+
+		if err := w.writeAnime(anime); err != nil {
+			return count, fmt.Errorf("failed to write anime %d: %v", anime.ID, err)
+		}
+
+		count++
+		if count%500 == 0 {
+			fmt.Printf("\rЭкспортировано: %d", count)
+		}
+	}
+	fmt.Println()
+	if err := scanner.Err(); err != nil {
+		return count, fmt.Errorf("failed to read snapshot: %v", err)
+	}
+
+	w.close()
+	if err := tx.Commit(); err != nil {
+		return count, fmt.Errorf("failed to commit: %v", err)
+	}
+
+	if _, err := conn.Exec("INSERT INTO titles_fts (titles_fts) VALUES ('optimize'); VACUUM;"); err != nil {
+		return count, fmt.Errorf("failed to optimize: %v", err)
+	}
+	conn.Close()
+
+	if err := os.Rename(tmpPath, outputPath); err != nil {
+		return count, fmt.Errorf("failed to rename output: %v", err)
+	}
+
+	return count, nil
+}
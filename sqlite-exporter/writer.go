@@ -0,0 +1,248 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"dimensi/db-aggregator/pkg/db"
+)
+
+// writer держит подготовленные запросы на время одной транзакции
+type writer struct {
+	stmts map[string]*sql.Stmt
+}
+
+var statements = map[string]string{
+	"meta": `INSERT INTO meta (key, value) VALUES (?, ?)`,
+	"anime": `INSERT INTO anime (id, mal_id, score, type, type_title, year, season, number_of_episodes, duration,
+		is_airing, aired_on, released_on, shikimori_updated_at, poster_anime365_original, poster_anime365_preview,
+		poster_shikimori_original, poster_shikimori_preview, franchise, banner_image, next_episode, next_episode_airing_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+	"externalID":  `INSERT INTO external_ids (anime_id, source, external_id) VALUES (?, ?, ?)`,
+	"title":       `INSERT INTO titles (anime_id, lang, title) VALUES (?, ?, ?)`,
+	"titleFTS":    `INSERT INTO titles_fts (title, anime_id, lang) VALUES (?, ?, ?)`,
+	"description": `INSERT INTO descriptions (anime_id, source, updated_date_time, value) VALUES (?, ?, ?, ?)`,
+	"episode": `INSERT INTO episodes (id, anime_id, number, type, title, first_uploaded_date_time, is_active, series_id,
+		is_first_uploaded, air_date, title_en, title_ja, title_romaji, rating, thumbnail, synopsis, length)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+	"genre":       `INSERT OR IGNORE INTO genres (id, title, url) VALUES (?, ?, ?)`,
+	"animeGenre":  `INSERT OR IGNORE INTO anime_genres (anime_id, genre_id) VALUES (?, ?)`,
+	"tag":         `INSERT INTO tags (anime_id, name, category, rank, spoiler) VALUES (?, ?, ?, ?, ?)`,
+	"studio":      `INSERT OR IGNORE INTO studios (id, name, filtered_name, image, real) VALUES (?, ?, ?, ?, ?)`,
+	"animeStudio": `INSERT OR IGNORE INTO anime_studios (anime_id, studio_id) VALUES (?, ?)`,
+	"character": `INSERT OR IGNORE INTO characters (id, name, russian, image_original, image_preview, image_x48, image_x96)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+	"role":       `INSERT INTO roles (anime_id, character_id, name, russian) VALUES (?, ?, ?, ?)`,
+	"person":     `INSERT OR IGNORE INTO persons (id, name, russian, image_original, image_preview) VALUES (?, ?, ?, ?, ?)`,
+	"seiyuu":     `INSERT INTO seiyuu (anime_id, character_id, person_id, language) VALUES (?, ?, ?, ?)`,
+	"staff":      `INSERT INTO staff (anime_id, person_id, name, russian) VALUES (?, ?, ?, ?)`,
+	"screenshot": `INSERT INTO screenshots (anime_id, position, original, preview) VALUES (?, ?, ?, ?)`,
+	"similar": `INSERT INTO similar (anime_id, position, mal_id, title_en, title_ru, score, image_original, image_preview)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+	"relation": `INSERT INTO relations (anime_id, relation, relation_russian, mal_id, title_en, title_ru, kind, aired_on,
+		image_original, image_preview) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+	"trailer": `INSERT INTO trailers (anime_id, id, hosting, kind, name, url, player_url, image_url)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+}
+
+func newWriter(tx *sql.Tx) (*writer, error) {
+	w := &writer{stmts: make(map[string]*sql.Stmt)}
+	for name, query := range statements {
+		stmt, err := tx.Prepare(query)
+		if err != nil {
+			w.close()
+			return nil, fmt.Errorf("failed to prepare %s statement: %v", name, err)
+		}
+		w.stmts[name] = stmt
+	}
+	return w, nil
+}
+
+func (w *writer) close() {
+	for name, stmt := range w.stmts {
+		stmt.Close()
+		delete(w.stmts, name)
+	}
+}
+
+func (w *writer) exec(name string, args ...interface{}) error {
+	if _, err := w.stmts[name].Exec(args...); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
+
+func (w *writer) writeMeta(sourceFile string) error {
+	meta := map[string]string{
+		"schema_version": strconv.Itoa(db.SchemaVersion),
+		"source_file":    sourceFile,
+		"exported_at":    strconv.FormatInt(time.Now().Unix(), 10),
+	}
+	for key, value := range meta {
+		if err := w.exec("meta", key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *writer) writeAnime(a db.Anime) error {
+	// Без ближайшей серии оба столбца NULL
+	var nextEpisode, nextEpisodeAiringAt interface{}
+	if a.NextEpisode != nil {
+		nextEpisode, nextEpisodeAiringAt = a.NextEpisode.Episode, a.NextEpisode.AiringAt
+	}
+
+	err := w.exec("anime", a.ID, a.MyAnimeListID, a.Score, a.Type, a.TypeTitle, a.Year, a.Season,
+		a.NumberOfEpisodes, a.Duration, a.IsAiring, a.AiredOn, a.ReleasedOn, a.ShikimoriUpdatedAt,
+		a.Poster.Anime365.Original, a.Poster.Anime365.Preview,
+		a.Poster.Shikimori.Original, a.Poster.Shikimori.Preview, a.Franchise,
+		a.BannerImage, nextEpisode, nextEpisodeAiringAt)
+	if err != nil {
+		return err
+	}
+
+	for source, id := range a.ExternalIDs.BySource() {
+		if id == 0 {
+			continue
+		}
+		if err := w.exec("externalID", a.ID, source, id); err != nil {
+			return err
+		}
+	}
+
+	for lang, title := range a.Titles {
+		if title == "" {
+			continue
+		}
+		if err := w.exec("title", a.ID, lang, title); err != nil {
+			return err
+		}
+		if err := w.exec("titleFTS", title, a.ID, lang); err != nil {
+			return err
+		}
+	}
+
+	// Альтернативные названия без языка, лишь бы находились через FTS
+	for _, title := range a.AllTitles {
+		if err := w.exec("title", a.ID, "alt", title); err != nil {
+			return err
+		}
+		if err := w.exec("titleFTS", title, a.ID, "alt"); err != nil {
+			return err
+		}
+	}
+
+	for _, d := range a.Descriptions {
+		if err := w.exec("description", a.ID, d.Source, d.UpdatedDateTime, d.Value); err != nil {
+			return err
+		}
+	}
+
+	for _, ep := range a.Episodes {
+		err := w.exec("episode", ep.ID, a.ID, ep.Number, ep.Type, ep.Title, ep.FirstUploadedDateTime,
+			ep.IsActive, ep.SeriesID, ep.IsFirstUploaded, ep.AirDate,
+			ep.Titles["en"], ep.Titles["ja"], ep.Titles["romaji"], ep.Rating, ep.Thumbnail, ep.Synopsis, ep.Length)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, g := range a.Genres {
+		if err := w.exec("genre", g.ID, g.Title, g.URL); err != nil {
+			return err
+		}
+		if err := w.exec("animeGenre", a.ID, g.ID); err != nil {
+			return err
+		}
+	}
+
+	for _, t := range a.Tags {
+		if err := w.exec("tag", a.ID, t.Name, t.Category, t.Rank, t.Spoiler); err != nil {
+			return err
+		}
+	}
+
+	for _, s := range a.Studios {
+		if err := w.exec("studio", s.ID, s.Name, s.FilteredName, s.Image, s.Real); err != nil {
+			return err
+		}
+		if err := w.exec("animeStudio", a.ID, s.ID); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range a.Roles {
+		c := r.Character
+		err := w.exec("character", c.ID, c.Name, c.Russian,
+			c.Image.Original, c.Image.Preview, c.Image.X48, c.Image.X96)
+		if err != nil {
+			return err
+		}
+		if len(r.RoleNames) == 0 {
+			if err := w.exec("role", a.ID, c.ID, "", ""); err != nil {
+				return err
+			}
+		}
+		for _, name := range r.RoleNames {
+			if err := w.exec("role", a.ID, c.ID, name.Name, name.Russian); err != nil {
+				return err
+			}
+		}
+		for _, s := range r.Seiyuu {
+			if err := w.writePerson(s.Person); err != nil {
+				return err
+			}
+			if err := w.exec("seiyuu", a.ID, c.ID, s.Person.ID, s.Language); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, st := range a.Staff {
+		if err := w.writePerson(st.Person); err != nil {
+			return err
+		}
+		for _, name := range st.RoleNames {
+			if err := w.exec("staff", a.ID, st.Person.ID, name.Name, name.Russian); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i, s := range a.Screenshots {
+		if err := w.exec("screenshot", a.ID, i, s.Original, s.Preview); err != nil {
+			return err
+		}
+	}
+
+	for i, s := range a.Similar {
+		err := w.exec("similar", a.ID, i, s.MyAnimeListID, s.Titles["en"], s.Titles["ru"], s.Score,
+			s.Image.Original, s.Image.Preview)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, r := range a.Relations {
+		err := w.exec("relation", a.ID, r.Relation, r.RelationRussian, r.MyAnimeListID, r.Titles["en"], r.Titles["ru"],
+			r.Kind, r.AiredOn, r.Image.Original, r.Image.Preview)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, t := range a.Trailers {
+		if err := w.exec("trailer", a.ID, t.ID, t.Hosting, t.Kind, t.Name, t.URL, t.PlayerURL, t.ImageURL); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *writer) writePerson(p db.Person) error {
+	return w.exec("person", p.ID, p.Name, p.Russian, p.Image.Original, p.Image.Preview)
+}